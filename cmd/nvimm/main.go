@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/candango/nvimm/internal/cli"
@@ -11,16 +14,41 @@ import (
 func main() {
 	var opts config.AppOptions
 
-	parser := flags.NewParser(&opts, flags.Default)
+	// Errors are printed below, so the exit status of a process run by nvimm
+	// is passed on without noise.
+	parser := flags.NewParser(&opts, flags.Default&^flags.PrintErrors)
 	parser.Usage = "[Options] command"
 
-	parser.CommandHandler = config.WithAppOptions(&opts, config.WithPathsResolved)
+	parser.CommandHandler = cli.WithUpdateNotice(&opts,
+		config.WithAppOptions(&opts, config.WithLogger, config.WithSignals,
+			config.WithHttpClient, config.WithCacheBackend,
+			config.WithPathsResolved))
 
+	parser.AddCommand(
+		"cache",
+		"Manage the downloads cache",
+		"List, measure, verify and clean the downloaded release assets, which are kept by their sha256 digest and reused by later installs instead of downloading them again.",
+		&cli.CacheCommand{})
+	parser.AddCommand(
+		"changelog",
+		"Show the release notes between two Neovim versions",
+		"Show the release notes of every release after <from> up to and including [to], which defaults to the latest release. Breaking changes and deprecations are highlighted and the output is paged on a terminal using $PAGER.",
+		&cli.ChangelogCommand{})
 	parser.AddCommand(
 		"current",
 		"Display the active or installed Neovim version",
 		"Show the version of Neovim currently in use or switch the active version to a specific installed build.",
 		&cli.CurrentCommand{})
+	parser.AddCommand(
+		"doctor",
+		"Diagnose the nvimm setup",
+		"Check the nvimm directories, the current release, PATH, other nvim binaries shadowing nvimm, broken installs, the releases cache and GitHub reachability, printing how to fix each problem. With --fix, safe repairs are applied.",
+		&cli.DoctorCommand{})
+	parser.AddCommand(
+		"info",
+		"Show details of a Neovim release",
+		"Show the release notes, assets, publish date and author of a Neovim release, the asset nvimm would install on this machine and whether it is installed or current.",
+		&cli.InfoCommand{})
 	parser.AddCommand(
 		"install",
 		"Install the latest or a specific Neovim version",
@@ -31,9 +59,53 @@ func main() {
 		"List Neovim installed versions",
 		"List all Neovim versions currently installed and managed by nvimm on this machine.",
 		&cli.ListCommand{})
+	parser.AddCommand(
+		"matrix",
+		"Run a command with several Neovim versions",
+		"Install each release listed in --versions when missing and run the command after -- with the nvim of that release first on PATH and NVIMM_RELEASE set to its name. The results are printed as a pass/fail table and optionally written as a JUnit XML report. Exits with status 1 when the command fails for a version.",
+		&cli.MatrixCommand{})
+	parser.AddCommand(
+		"outdated",
+		"List installed Neovim versions with newer releases",
+		"Compare every installed Neovim version against the newest patch of its minor line and the stable release.",
+		&cli.OutdatedCommand{})
+	parser.AddCommand(
+		"run",
+		"Run an installed Neovim version",
+		"Run the nvim binary of an installed release without changing current. Arguments after -- are passed to nvim and nvimm exits with its status. With --install, a release that is not installed is installed first.",
+		&cli.RunCommand{})
+	parser.AddCommand(
+		"upgrade",
+		"Upgrade the current Neovim release",
+		"Install the newest release, the newest patch of the current minor line or the stable release and set it as current. Upgrading nightly keeps the replaced build as a rollback snapshot. Exits with status 3 when already up to date.",
+		&cli.UpgradeCommand{})
+	parser.AddCommand(
+		"which",
+		"Print the path of an installed Neovim binary",
+		"Print the path of the nvim binary of the installed release matching <release>, which may also name an install directory like nightly.previous.",
+		&cli.WhichCommand{})
 
 	_, err := parser.Parse()
 	if err != nil {
+		var exitErr *cli.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			fmt.Fprintln(os.Stdout, err)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if errors.Is(err, context.Canceled) {
+			os.Exit(cli.ExitInterrupted)
+		}
+		if errors.Is(err, cli.ErrUpToDate) {
+			os.Exit(cli.ExitUpToDate)
+		}
+		if errors.Is(err, cli.ErrProblemsFound) || errors.Is(err, cli.ErrMatrixFailed) ||
+			errors.Is(err, cli.ErrCacheCorrupted) {
+			os.Exit(1)
+		}
 		if flagsErr, ok := err.(*flags.Error); ok && (flagsErr.Type == flags.ErrUnknownCommand || flagsErr.Type == flags.ErrUnknownFlag) {
 			parser.WriteHelp(os.Stderr)
 			os.Exit(1)
@@ -42,6 +114,6 @@ func main() {
 			os.Exit(0)
 		}
 		parser.WriteHelp(os.Stderr)
-		os.Exit(0)
+		os.Exit(1)
 	}
 }
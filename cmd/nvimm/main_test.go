@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candango/nvimm/internal/githubtest"
+	"github.com/candango/nvimm/internal/lock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain runs nvimm instead of the tests when the e2e tests run the test
+// binary as the nvimm command.
+func TestMain(m *testing.M) {
+	if os.Getenv("NVIMM_E2E") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// e2e runs nvimm commands against a fake GitHub in temporary directories.
+type e2e struct {
+	t    *testing.T
+	srv  *githubtest.Server
+	root string
+}
+
+func newE2E(t *testing.T) *e2e {
+	t.Helper()
+	if !(runtime.GOOS == "linux" && runtime.GOARCH == "amd64") &&
+		!(runtime.GOOS == "darwin" && runtime.GOARCH == "arm64") {
+		t.Skip("the recorded releases have no asset for this platform")
+	}
+	data, err := os.ReadFile(filepath.Join("..", "..", "internal", "cli",
+		"testdata", "releases.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &e2e{t: t, srv: githubtest.NewServer(t, data), root: t.TempDir()}
+}
+
+func (e *e2e) path(elem ...string) string {
+	return filepath.Join(append([]string{e.root, "nvimm"}, elem...)...)
+}
+
+// command returns the command running nvimm with the arguments.
+func (e *e2e) command(args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = []string{}
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "NVIMM_") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	cmd.Env = append(cmd.Env,
+		"NVIMM_E2E=1",
+		"NVIMM_API_URL="+e.srv.URL,
+		"NVIMM_PATH="+e.path(),
+		"NVIMM_CACHE_PATH="+filepath.Join(e.root, "cache"),
+		"NVIMM_CONFIG_DIR="+filepath.Join(e.root, "config"),
+		"NVIMM_MAX_BACKOFF=10ms",
+		"NO_COLOR=1",
+	)
+	return cmd
+}
+
+// run runs nvimm with the arguments and returns its standard output, its
+// standard error and its exit status.
+func (e *e2e) run(args ...string) (string, string, int) {
+	e.t.Helper()
+	cmd := e.command(args...)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	code := e.wait(cmd, cmd.Run())
+	return stdout.String(), stderr.String(), code
+}
+
+// wait returns the exit status of the command from the error returned when
+// it finished.
+func (e *e2e) wait(cmd *exec.Cmd, err error) int {
+	e.t.Helper()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	} else if err != nil {
+		e.t.Fatal(err)
+	}
+	return 0
+}
+
+// asset returns the name of the 0.11.5 asset installed on this platform.
+func (e *e2e) asset() string {
+	if runtime.GOOS == "darwin" {
+		return "nvim-macos-arm64.tar.gz"
+	}
+	return "nvim-linux-x86_64.tar.gz"
+}
+
+// fail injects the fault in the next downloads of the 0.11.5 asset, every
+// download if times is zero.
+func (e *e2e) fail(fault githubtest.Fault, times int) {
+	e.srv.FailTimes("v0.11.5", e.asset(), fault, times)
+}
+
+// downloads returns how many times the 0.11.5 asset was requested.
+func (e *e2e) downloads() int {
+	return e.srv.Hits("/neovim/neovim/releases/download/v0.11.5/" +
+		e.asset())
+}
+
+func (e *e2e) mkdirs() {
+	e.t.Helper()
+	for _, dir := range []string{e.path(), filepath.Join(e.root, "cache")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			e.t.Fatal(err)
+		}
+	}
+}
+
+func TestE2E(t *testing.T) {
+
+	t.Run("should install, list and switch releases", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		out, stderr, code := e.run("install", "0.11")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, out, "Resolved 0.11 to 0.11.5.")
+		assert.Contains(t, out, "Version 0.11.5 set as current.")
+		assert.Contains(t, out, "Installed 0.11.5 in")
+		assert.NotContains(t, out, "[FAIL]")
+		assert.FileExists(t, e.path("0.11.5", "bin", "nvim"))
+
+		out, stderr, code = e.run("install", "0.10.4")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, out, "Current release not changed. [SKIP]")
+
+		out, _, code = e.run("list", "--installed")
+		assert.Equal(t, 0, code)
+		assert.Contains(t, out, "* 0.11.5")
+		assert.Contains(t, out, "  0.10.4")
+
+		out, _, code = e.run("current")
+		assert.Equal(t, 0, code)
+		assert.Contains(t, out, "0.11.5")
+
+		_, stderr, code = e.run("current", "0.10.4")
+		assert.Equal(t, 0, code, stderr)
+		out, _, _ = e.run("-o", "json", "current")
+		assert.Contains(t, out, `"version": "0.10.4"`)
+
+		out, _, code = e.run("which", "0.10")
+		assert.Equal(t, 0, code)
+		assert.Equal(t, e.path("0.10.4", "bin", "nvim")+"\n", out)
+	})
+
+	t.Run("should fail when the asset is missing", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		e.fail(githubtest.FaultNotFound, 0)
+		out, stderr, code := e.run("install", "0.11.5")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, out, "[FAIL]")
+		assert.Contains(t, out, "Install of 0.11.5 failed at download")
+		assert.Contains(t, stderr, "404 Not Found")
+		assert.NoFileExists(t, e.path("0.11.5", "bin", "nvim"))
+	})
+
+	t.Run("should fail on a digest mismatch", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		e.fail(githubtest.FaultCorrupt, 0)
+		out, stderr, code := e.run("-o", "json", "install", "0.11.5")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, out, `"status": "failed"`)
+		assert.Contains(t, out, `"outcome": "fail"`)
+		assert.Contains(t, stderr, "the downloaded file is corrupted")
+		assert.Equal(t, 2, e.downloads())
+		assert.NoFileExists(t, e.path("0.11.5", "bin", "nvim"))
+	})
+
+	t.Run("should fail on a truncated download", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		e.fail(githubtest.FaultTruncate, 0)
+		out, stderr, code := e.run("install", "0.11.5")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, out, "Install of 0.11.5 failed at download")
+		assert.Contains(t, stderr, "unexpected EOF")
+		assert.Equal(t, 4, e.downloads())
+		assert.NoFileExists(t, e.path("0.11.5", "bin", "nvim"))
+	})
+
+	t.Run("should recover from transient failures", func(t *testing.T) {
+		for _, fault := range []githubtest.Fault{githubtest.FaultUnavailable,
+			githubtest.FaultTruncate} {
+			e := newE2E(t)
+			e.mkdirs()
+			e.fail(fault, 2)
+			out, stderr, code := e.run("-v", "install", "0.11.5")
+			assert.Equal(t, 0, code, stderr)
+			assert.Contains(t, out, "Installed 0.11.5 in")
+			assert.Contains(t, stderr, "retry=2 of=3")
+			assert.Equal(t, 3, e.downloads())
+			assert.FileExists(t, e.path("0.11.5", "bin", "nvim"))
+		}
+
+		e := newE2E(t)
+		e.mkdirs()
+		e.fail(githubtest.FaultCorrupt, 1)
+		out, stderr, code := e.run("install", "0.11.5")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, out, "Checksum verified after a mismatch, "+
+			e.asset()+" downloaded again")
+		assert.Contains(t, out, "1 warned")
+		assert.Equal(t, 2, e.downloads())
+	})
+
+	t.Run("should reuse cached downloads", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		_, stderr, code := e.run("install", "0.11.5")
+		assert.Equal(t, 0, code, stderr)
+		assert.NoError(t, os.RemoveAll(e.path("0.11.5")))
+
+		out, stderr, code := e.run("install", "0.11.5")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, out, "Using cached "+filepath.Join(e.root, "cache",
+			"downloads"))
+		assert.Equal(t, 1, e.downloads())
+		assert.FileExists(t, e.path("0.11.5", "bin", "nvim"))
+
+		out, _, code = e.run("-o", "json", "cache", "list")
+		assert.Equal(t, 0, code)
+		assert.Contains(t, out, `"asset": "`+e.asset()+`"`)
+		assert.Contains(t, out, `"0.11.5"`)
+		_, stderr, code = e.run("cache", "verify")
+		assert.Equal(t, 0, code, stderr)
+
+		out, stderr, code = e.run("cache", "clean")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, out, "Removed 1 files")
+		out, _, _ = e.run("cache", "list")
+		assert.Contains(t, out, "No cached downloads.")
+	})
+
+	t.Run("should wait for another nvimm", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		l, err := lock.Acquire(context.Background(), e.path(), 0)
+		assert.NoError(t, err)
+		cmd := e.command("install", "0.11.5")
+		cmd.Env = append(cmd.Env, "NVIMM_LOCK_TIMEOUT=50ms")
+		out, err := cmd.Output()
+		assert.Equal(t, 1, e.wait(cmd, err))
+		assert.Contains(t, string(out), fmt.Sprintf(
+			"Waiting for another nvimm (pid %d) to finish...", os.Getpid()))
+		assert.Contains(t, string(err.(*exec.ExitError).Stderr), fmt.Sprintf(
+			"another nvimm is running (pid %d)", os.Getpid()))
+		assert.Contains(t, string(out), "Resolved 0.11.5 to 0.11.5.")
+		assert.Equal(t, 0, e.downloads())
+
+		time.AfterFunc(200*time.Millisecond, func() { l.Release() })
+		_, stderr, code := e.run("install", "0.11.5")
+		assert.Equal(t, 0, code, stderr)
+		assert.FileExists(t, e.path("0.11.5", "bin", "nvim"))
+	})
+
+	t.Run("should heal a corrupt releases cache", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		_, stderr, code := e.run("list")
+		assert.Equal(t, 0, code, stderr)
+		cacheFile := filepath.Join(e.root, "cache", "nvimm_releases.json")
+		assert.NoError(t, os.WriteFile(cacheFile,
+			[]byte(`{"message":"API rate limit exceeded"}`), 0644))
+
+		e.srv.SetRateLimited(true)
+		out, stderr, code := e.run("-v", "list")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, out, "0.11.5")
+		assert.Contains(t, stderr, "discarding the corrupt releases cache")
+		assert.Contains(t, stderr, "using the last known good releases")
+
+		e.srv.SetRateLimited(false)
+		_, stderr, code = e.run("list")
+		assert.Equal(t, 0, code, stderr)
+		assert.Empty(t, stderr)
+		data, err := os.ReadFile(cacheFile)
+		assert.NoError(t, err)
+		assert.Contains(t, string(data), `"tag_name"`)
+	})
+
+	t.Run("should upgrade nightly and remove the old build", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		_, stderr, code := e.run("install", "nightly")
+		assert.Equal(t, 0, code, stderr)
+		// outdate makes the installed nightly an older build.
+		outdate := func() {
+			t.Helper()
+			record := e.path("nightly", ".nvimm.json")
+			data, err := os.ReadFile(record)
+			assert.NoError(t, err)
+			data = regexp.MustCompile(`"commit": "[0-9a-f]+"`).ReplaceAll(
+				data, []byte(`"commit": "0000000"`))
+			assert.NoError(t, os.WriteFile(record, data, 0644))
+		}
+
+		outdate()
+		out, stderr, code := e.run("upgrade", "nightly")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, out, "Previous build kept at:")
+		assert.DirExists(t, e.path("nightly.previous"))
+
+		outdate()
+		out, stderr, code = e.run("upgrade", "nightly", "--remove-old")
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, out, "Removed "+e.path("nightly.previous"))
+		assert.NoDirExists(t, e.path("nightly.previous"))
+		assert.FileExists(t, e.path("nightly", "bin", "nvim"))
+
+		_, stderr, code = e.run("upgrade", "nightly", "--rollback",
+			"--remove-old")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "mutually exclusive")
+	})
+
+	t.Run("should diagnose and create a missing config dir",
+		func(t *testing.T) {
+			e := newE2E(t)
+			e.mkdirs()
+			configDir := filepath.Join(e.root, "config")
+			stdout, _, code := e.run("doctor", "--offline")
+			assert.Equal(t, 1, code)
+			assert.Contains(t, stdout, "[fail]  config dir "+configDir+
+				" does not exist")
+			assert.NoDirExists(t, configDir)
+
+			// The current bin dir of the test is not on PATH, which fails
+			// apart from the config dir.
+			stdout, _, code = e.run("doctor", "--offline", "--fix")
+			assert.Equal(t, 1, code)
+			assert.Contains(t, stdout, "[fixed] config dir created "+
+				configDir)
+			assert.Contains(t, stdout, "1 problem found")
+			assert.DirExists(t, configDir)
+		})
+
+	t.Run("should explain the resolution of queries", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		for _, args := range [][]string{
+			{"-v", "info", "^0.10"},
+			{"-v", "changelog", "--no-pager", "^0.10", "0.11"},
+		} {
+			_, stderr, code := e.run(args...)
+			assert.Equal(t, 0, code, stderr)
+			assert.Contains(t, stderr, "resolving release")
+			assert.Contains(t, stderr, "release selected")
+			assert.Contains(t, stderr, "release rejected")
+		}
+	})
+
+	t.Run("should report the exceeded rate limit", func(t *testing.T) {
+		e := newE2E(t)
+		e.mkdirs()
+		e.srv.SetRateLimited(true)
+		_, stderr, code := e.run("list")
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stderr, "GitHub API rate limit exceeded")
+	})
+
+	t.Run("should clean up when interrupted", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("interrupting a process is not supported on windows")
+		}
+		e := newE2E(t)
+		e.mkdirs()
+		e.fail(githubtest.FaultStall, 0)
+		cmd := e.command("install", "0.11.5")
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		parts := filepath.Join(e.root, "cache", "*.part")
+		assert.Eventually(t, func() bool {
+			matches, _ := filepath.Glob(parts)
+			return len(matches) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, 130, e.wait(cmd, cmd.Wait()))
+		assert.Contains(t, stderr.String(), "download of nvim-")
+		assert.NotContains(t, stderr.String(), "Usage:")
+		matches, _ := filepath.Glob(parts)
+		assert.Empty(t, matches)
+		assert.NoDirExists(t, e.path("0.11.5"))
+	})
+}
@@ -4,18 +4,19 @@ go 1.23
 
 require (
 	github.com/candango/gopeasant v0.2.9
-	github.com/candango/httpok v0.1.0
 	github.com/candango/iook v0.0.3
 	github.com/jessevdk/go-flags v1.6.1
+	github.com/redis/go-redis/v9 v9.7.3
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/sys v0.21.0
+	golang.org/x/term v0.21.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/candango/httpok v0.1.0 // indirect
 	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/redis/go-redis/v9 v9.7.3 // indirect
-	golang.org/x/sys v0.21.0 // indirect
 )
@@ -0,0 +1,125 @@
+package installer_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/candango/nvimm/installer"
+	"github.com/candango/nvimm/release"
+	"github.com/stretchr/testify/assert"
+)
+
+// tarball returns a gzipped tarball with the nvim binary under the
+// directory.
+func tarball(t *testing.T, dir string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	content := []byte("#!/bin/sh\n")
+	for _, hdr := range []*tar.Header{
+		{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: dir + "/bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: dir + "/bin/nvim", Typeflag: tar.TypeReg, Mode: 0755,
+			Size: int64(len(content))},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestEmbedding installs a release through the exported API only, the way
+// tools embedding the installer do.
+func TestEmbedding(t *testing.T) {
+	data := tarball(t, "nvim-linux-x86_64")
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	assetPath := "/neovim/neovim/releases/download/v0.11.5/" +
+		"nvim-linux-x86_64.tar.gz"
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	listing, err := json.Marshal(release.Releases{
+		{TagName: "stable", Name: "Nvim 0.11.5"},
+		{TagName: "v0.11.5", Name: "Nvim 0.11.5",
+			HtmlUrl: srv.URL + "/neovim/neovim/releases/tag/v0.11.5",
+			Assets: []release.Asset{{
+				Name:               "nvim-linux-x86_64.tar.gz",
+				Size:               float64(len(data)),
+				Digest:             digest,
+				BrowserDownloadUrl: srv.URL + assetPath,
+			}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("GET /releases", func(w http.ResponseWriter,
+		r *http.Request) {
+		w.Write(listing)
+	})
+	mux.HandleFunc("GET "+assetPath, func(w http.ResponseWriter,
+		r *http.Request) {
+		w.Write(data)
+	})
+
+	root := t.TempDir()
+	inst := installer.New(filepath.Join(root, "nvimm"),
+		filepath.Join(root, "cache"))
+	inst.ReleasesUrl = srv.URL + "/releases"
+	inst.Client = srv.Client()
+	inst.OS, inst.Arch = "linux", "amd64"
+	inst.Retry = installer.RetryPolicy{Retries: 1}
+	ctx := context.Background()
+
+	t.Run("should install and activate a release", func(t *testing.T) {
+		releases, err := inst.FetchReleases(ctx)
+		assert.NoError(t, err)
+		var info *release.Info
+		var res *release.Resolution
+		info, res, err = inst.Resolve(releases, "stable")
+		assert.NoError(t, err)
+		assert.Equal(t, "stable", res.Query)
+		assert.Equal(t, "0.11.5", info.CleanTagName())
+
+		var asset *release.Asset
+		asset, err = inst.Asset(info)
+		assert.NoError(t, err)
+		file, err := inst.Download(ctx, info, asset, nil)
+		assert.NoError(t, err)
+		_, err = inst.Verify(file, asset)
+		assert.NoError(t, err)
+		extracted, err := inst.Extract(ctx, file)
+		assert.NoError(t, err)
+		var record *release.InstallRecord
+		record, err = inst.Place(info, asset, extracted)
+		assert.NoError(t, err)
+		assert.Equal(t, asset.Digest, record.Digest)
+
+		assert.NoError(t, inst.Activate(info.CleanTagName()))
+		current, err := inst.Current()
+		assert.NoError(t, err)
+		assert.Equal(t, "0.11.5", current)
+		_, err = os.Stat(filepath.Join(inst.Path("0.11.5"), "bin", "nvim"))
+		assert.NoError(t, err)
+	})
+}
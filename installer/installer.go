@@ -0,0 +1,551 @@
+// Package installer installs Neovim releases published on GitHub. It exposes
+// each step of an install, resolving the release, downloading its asset for
+// the platform, verifying the checksum, extracting and placing the files and
+// activating the release, so tools other than the nvimm command line can
+// embed it. The HTTP client, the directories and the clock are injected.
+// Network and extraction steps take a context, canceling it stops them and
+// removes their partial files.
+package installer
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/candango/iook/archive"
+	"github.com/candango/iook/dir"
+	"github.com/candango/nvimm/internal/cache"
+	"github.com/candango/nvimm/internal/filehash"
+	"github.com/candango/nvimm/internal/logging"
+	"github.com/candango/nvimm/internal/protocol"
+	"github.com/candango/nvimm/release"
+)
+
+// CurrentLink is the name of the symlink, relative to the root, pointing to
+// the active release.
+const CurrentLink = "current"
+
+// DownloadsDir is the directory, relative to the cache path, of the store
+// keeping the downloaded assets by digest.
+const DownloadsDir = "downloads"
+
+// extractPrefix prefixes the temporary directories tarballs are extracted
+// to.
+const extractPrefix = "extract-"
+
+var (
+	// ErrNoAsset is returned when a release has no asset for the platform.
+	ErrNoAsset = errors.New("no asset for the platform")
+	// ErrNoChecksum is returned by Verify when the asset has no published
+	// checksum to verify against.
+	ErrNoChecksum = errors.New("no checksum published")
+	// ErrChecksumMismatch is returned by Verify when the downloaded file does
+	// not match the published checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrRateLimited is returned by FetchListing when the GitHub API rate
+	// limit is exceeded.
+	ErrRateLimited = protocol.ErrRateLimited
+)
+
+// RetryPolicy tells how many times and how long apart downloads are
+// retried.
+type RetryPolicy struct {
+	// Retries is how many times a failed download is retried.
+	Retries int
+	// BaseBackoff is the wait before the first retry, 500ms if zero. It
+	// doubles on every retry.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the wait between retries. Zero means no cap.
+	MaxBackoff time.Duration
+}
+
+// Installer installs releases under Root, downloading the assets into
+// CachePath.
+type Installer struct {
+	// Root is the directory releases are installed to, each in a directory
+	// named after the release.
+	Root string
+	// CachePath is the directory assets are downloaded and extracted to,
+	// downloads are kept by digest in its DownloadsDir.
+	CachePath string
+	// ReleasesUrl is the GitHub API endpoint listing the Neovim releases.
+	ReleasesUrl string
+	// MinRelease is the oldest release returned by FetchReleases.
+	MinRelease string
+	// Client performs the HTTP requests.
+	Client *http.Client
+	// Now returns the current time, recorded as the install time.
+	Now func() time.Time
+	// OS and Arch select the asset installed, they follow GOOS and GOARCH.
+	OS   string
+	Arch string
+	// Retry tells how downloads interrupted by a transient error are
+	// retried. Requests failing before the response are retried by the
+	// Client, when its transport does.
+	Retry RetryPolicy
+	// Logger receives the log messages.
+	Logger *slog.Logger
+}
+
+// New creates an Installer for the running platform installing releases
+// under root and downloading assets into cachePath.
+func New(root string, cachePath string) *Installer {
+	releasesUrl := protocol.ReleasesRepoUrl(protocol.GithubApiUrl) +
+		"/releases"
+	return &Installer{
+		Root:        root,
+		CachePath:   cachePath,
+		ReleasesUrl: releasesUrl,
+		MinRelease:  "0.7.0",
+		Client:      http.DefaultClient,
+		Now:         time.Now,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		Logger:      logging.Discard(),
+	}
+}
+
+// Path returns the directory the release named name is installed to.
+func (i *Installer) Path(name string) string {
+	return filepath.Join(i.Root, name)
+}
+
+// FetchListing fetches the releases listing from ReleasesUrl and validates
+// it, so an error payload or a truncated response is never returned. An
+// exceeded GitHub rate limit fails with an error wrapping ErrRateLimited,
+// telling when it resets.
+func (i *Installer) FetchListing(ctx context.Context) ([]byte, error) {
+	i.Logger.Info("fetching releases", "url", i.ReleasesUrl)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		i.ReleasesUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get releases: %w", err)
+	}
+	res, err := i.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get releases: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to get releases: %w",
+			protocol.StatusError(res))
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read releases: %w", err)
+	}
+	if err := release.ValidateListing(data); err != nil {
+		return nil, fmt.Errorf("failed to get releases: %w", err)
+	}
+	return data, nil
+}
+
+// FetchReleases fetches the releases listing like FetchListing and returns
+// its releases, discarding the ones older than MinRelease.
+func (i *Installer) FetchReleases(ctx context.Context) (release.Releases,
+	error) {
+	data, err := i.FetchListing(ctx)
+	if err != nil {
+		return nil, err
+	}
+	releases := release.Releases{}
+	err = releases.Process(data, i.MinRelease, i.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process releases: %w", err)
+	}
+	return releases, nil
+}
+
+// Resolve returns the release matching the query, which may be a version, a
+// version constraint or an alias like "stable". The resolution explaining
+// why the other releases were rejected is logged.
+func (i *Installer) Resolve(releases release.Releases,
+	query string) (*release.Info, *release.Resolution, error) {
+	res, err := releases.Resolve(query)
+	i.Logger.Info("resolving release", "query", res.Query, "constraint",
+		res.Constraint.String())
+	for _, candidate := range res.Candidates {
+		if candidate.Selected {
+			i.Logger.Info("release selected", "release",
+				candidate.Info.CleanTagName())
+			continue
+		}
+		i.Logger.Info("release rejected", "release",
+			candidate.Info.CleanTagName(), "reason", candidate.Reason)
+	}
+	if err != nil {
+		return nil, res, fmt.Errorf("release %s does not exists: %w", query,
+			err)
+	}
+	return res.Selected, res, nil
+}
+
+// Asset returns the asset of the release for the platform.
+func (i *Installer) Asset(info *release.Info) (*release.Asset, error) {
+	if asset := PlatformAsset(info, i.OS, i.Arch); asset != nil {
+		return asset, nil
+	}
+	return nil, fmt.Errorf("%w: the os %s and arch %s cannot to be "+
+		"resolved as a valid nvim asset", ErrNoAsset, i.OS, i.Arch)
+}
+
+// store returns the content-addressed store of the downloaded assets, under
+// the DownloadsDir of CachePath.
+func (i *Installer) store() *cache.Store {
+	store := cache.NewStore(filepath.Join(i.CachePath, DownloadsDir))
+	store.Logger = i.Logger
+	return store
+}
+
+// Cached returns the path of the asset in the store if it was downloaded
+// before and still matches its published digest.
+func (i *Installer) Cached(asset *release.Asset) (string, bool) {
+	if asset.Digest == "" {
+		return "", false
+	}
+	return i.store().Lookup(asset.Digest, asset.Name)
+}
+
+// Download downloads the asset of the release into the store and returns the
+// path of the downloaded file. An asset already in the store matching its
+// published digest is returned without downloading it. The bytes are also
+// written to progress, which may be nil. The file is written with a .part
+// suffix, removed if the download fails or the context is canceled, and
+// moved into the store when complete, so an interrupted download is never
+// left in place of the asset. A download interrupted by a transient error is
+// started again as Retry allows.
+func (i *Installer) Download(ctx context.Context, info *release.Info,
+	asset *release.Asset, progress io.Writer) (string, error) {
+	if file, ok := i.Cached(asset); ok {
+		i.Logger.Info("using cached download", "release", info.TagName,
+			"asset", asset.Name, "path", file)
+		return file, nil
+	}
+	if err := os.MkdirAll(i.CachePath, 0755); err != nil {
+		return "", err
+	}
+	url := AssetUrl(info, asset)
+	partPath := filepath.Join(i.CachePath, path.Base(url)+".part")
+	i.Logger.Info("downloading release", "release", info.TagName, "asset",
+		asset.Name)
+	reported := &reportedWriter{w: progress}
+	for retry := 1; ; retry++ {
+		reported.written = 0
+		err := i.download(ctx, url, partPath, reported)
+		if err == nil {
+			var file string
+			file, _, err = i.store().Put(partPath, asset.Name)
+			if err == nil {
+				return file, nil
+			}
+		}
+		os.Remove(partPath)
+		if retry > i.Retry.Retries || !protocol.Transient(err) ||
+			ctx.Err() != nil {
+			return "", err
+		}
+		backoff := protocol.RetryPolicy(i.Retry).Backoff(retry)
+		i.Logger.Info("retrying download", "asset", asset.Name, "retry",
+			retry, "of", i.Retry.Retries, "reason", err.Error(), "backoff",
+			backoff)
+		if err := protocol.Wait(ctx, backoff); err != nil {
+			return "", err
+		}
+	}
+}
+
+// download writes the body of the url to the file and to progress.
+func (i *Installer) download(ctx context.Context, url string, file string,
+	progress io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := i.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode > 299 {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.MultiWriter(out, progress), resp.Body)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// reportedWriter writes to w only the bytes past the ones already written,
+// so a restarted download does not report its progress twice. A nil w
+// discards everything.
+type reportedWriter struct {
+	w        io.Writer
+	written  int64
+	reported int64
+}
+
+func (rw *reportedWriter) Write(p []byte) (int, error) {
+	start := rw.written
+	rw.written += int64(len(p))
+	if rw.w == nil || rw.written <= rw.reported {
+		return len(p), nil
+	}
+	if skip := rw.reported - start; skip > 0 {
+		p = p[skip:]
+	}
+	rw.reported = rw.written
+	if _, err := rw.w.Write(p); err != nil {
+		return 0, err
+	}
+	return int(rw.written - start), nil
+}
+
+// Verify checks the file against the checksum published for the asset and
+// returns its checksum. ErrNoChecksum is returned when the asset has no
+// published checksum.
+func (i *Installer) Verify(file string, asset *release.Asset) (string,
+	error) {
+	if asset.Digest == "" {
+		return "", fmt.Errorf("%w for %s", ErrNoChecksum, asset.Name)
+	}
+	fingerprint, err := filehash.SHA256(file)
+	if err != nil {
+		return "", fmt.Errorf("checksum calculation failed: %w", err)
+	}
+	i.Logger.Debug("checksum verified", "file", file, "expected",
+		asset.Digest, "actual", fingerprint)
+	if fingerprint != asset.Digest {
+		return fingerprint, fmt.Errorf("%w: the downloaded file is "+
+			"corrupted, expected %s but got %s", ErrChecksumMismatch,
+			asset.Digest, fingerprint)
+	}
+	return fingerprint, nil
+}
+
+// VerifyOrRedownload verifies the file like Verify and, on a checksum
+// mismatch, downloads the asset again once, as the mismatch may come from a
+// transfer corrupted on the way, and verifies the new file. A file not
+// matching the checksum is removed from the store. It returns the path of
+// the verified file, its checksum and whether the asset was downloaded
+// again.
+func (i *Installer) VerifyOrRedownload(ctx context.Context,
+	info *release.Info, asset *release.Asset, file string) (string, string,
+	bool, error) {
+	fingerprint, err := i.Verify(file, asset)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		return file, fingerprint, false, err
+	}
+	i.Logger.Warn("checksum mismatch, downloading again", "asset",
+		asset.Name, "expected", asset.Digest, "actual", fingerprint)
+	i.discard(file)
+	file, err = i.Download(ctx, info, asset, nil)
+	if err != nil {
+		return "", "", true, err
+	}
+	fingerprint, err = i.Verify(file, asset)
+	if err != nil {
+		i.discard(file)
+	}
+	return file, fingerprint, true, err
+}
+
+// discard removes the file from the store, files elsewhere are kept.
+func (i *Installer) discard(file string) {
+	store := i.store()
+	if filepath.Dir(filepath.Dir(file)) == filepath.Clean(store.Dir) {
+		store.Remove(cache.Entry{Path: file})
+	}
+}
+
+// Extract extracts the downloaded tarball into a new directory under
+// CachePath and returns the directory holding the release files, named after
+// the tarball. The extracted files are removed if extraction fails or the
+// context is canceled, and by Place once copied.
+func (i *Installer) Extract(ctx context.Context, file string) (string,
+	error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(i.CachePath, 0755); err != nil {
+		return "", fmt.Errorf("extraction failed: %w", err)
+	}
+	tmp, err := os.MkdirTemp(i.CachePath, extractPrefix)
+	if err != nil {
+		return "", fmt.Errorf("extraction failed: %w", err)
+	}
+	gzr, err := gzip.NewReader(&contextReader{ctx: ctx, r: f})
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", fmt.Errorf("extraction failed: %w", err)
+	}
+	defer gzr.Close()
+	if err := archive.Untar(gzr, tmp); err != nil {
+		os.RemoveAll(tmp)
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return "", fmt.Errorf("extraction failed: %w", err)
+	}
+	return filepath.Join(tmp, strings.TrimSuffix(filepath.Base(file),
+		".tar.gz")), nil
+}
+
+// contextReader is a reader failing with the context error once the context
+// is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// Place copies the extracted release files into the release directory and
+// writes the install record there, which is returned. Files extracted by
+// Extract are removed once copied.
+func (i *Installer) Place(info *release.Info, asset *release.Asset,
+	extracted string) (*release.InstallRecord, error) {
+	dest := i.Path(info.CleanTagName())
+	if err := dir.CopyAll(extracted, dest); err != nil {
+		return nil, fmt.Errorf("copying files to %s failed: %w", dest, err)
+	}
+	if tmp := filepath.Dir(extracted); filepath.Dir(tmp) ==
+		filepath.Clean(i.CachePath) &&
+		strings.HasPrefix(filepath.Base(tmp), extractPrefix) {
+		os.RemoveAll(tmp)
+	}
+	record := release.NewInstallRecord(info, asset, i.Now())
+	if err := record.Write(dest); err != nil {
+		return nil, fmt.Errorf("failed to write install record: %w", err)
+	}
+	i.Logger.Info("release installed", "release", info.TagName, "path",
+		dest)
+	return record, nil
+}
+
+// Install runs every step but Resolve and Activate, accepting assets without
+// a published checksum and downloading again once on a checksum mismatch.
+// The context is checked before placing the files.
+func (i *Installer) Install(ctx context.Context, info *release.Info,
+	progress io.Writer) (*release.InstallRecord, error) {
+	asset, err := i.Asset(info)
+	if err != nil {
+		return nil, err
+	}
+	file, err := i.Download(ctx, info, asset, progress)
+	if err != nil {
+		return nil, err
+	}
+	file, _, _, err = i.VerifyOrRedownload(ctx, info, asset, file)
+	if err != nil && !errors.Is(err, ErrNoChecksum) {
+		return nil, err
+	}
+	extracted, err := i.Extract(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return i.Place(info, asset, extracted)
+}
+
+// Activate points the current symlink to the installed release.
+func (i *Installer) Activate(name string) error {
+	currentPath := filepath.Join(i.Root, CurrentLink)
+	if err := os.RemoveAll(currentPath); err != nil {
+		return fmt.Errorf("failed to remove current symlink: %w", err)
+	}
+	err := os.Symlink(i.Path(name), currentPath)
+	if err != nil {
+		return fmt.Errorf("failed to set %s as current: %w", name, err)
+	}
+	return nil
+}
+
+// Current returns the name of the release the current symlink points to, or
+// an empty string if no release is active.
+func (i *Installer) Current() (string, error) {
+	target, err := os.Readlink(filepath.Join(i.Root, CurrentLink))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read current symlink: %w", err)
+		}
+		return "", nil
+	}
+	return filepath.Base(target), nil
+}
+
+// PlatformAsset returns the asset of the release for the platform, or nil if
+// the release has none.
+func PlatformAsset(info *release.Info, goos string,
+	goarch string) *release.Asset {
+	name := TarballName(info, goos, goarch)
+	for i := range info.Assets {
+		if info.Assets[i].Name == name {
+			return &info.Assets[i]
+		}
+	}
+	return nil
+}
+
+// AssetUrl returns the download url of the release asset.
+func AssetUrl(info *release.Info, asset *release.Asset) string {
+	if asset.BrowserDownloadUrl != "" {
+		return asset.BrowserDownloadUrl
+	}
+	return fmt.Sprintf("%s/%s",
+		strings.ReplaceAll(info.HtmlUrl, "tag", "download"), asset.Name)
+}
+
+// linuxX86_64TarballVersion is the first release publishing the linux amd64
+// tarball as nvim-linux-x86_64 instead of nvim-linux64.
+var linuxX86_64TarballVersion = release.MustParseVersion("0.10.4")
+
+// TarballName returns the name of the release tarball for the platform, or
+// an empty string if none is published.
+func TarballName(info *release.Info, goos string, goarch string) string {
+
+	if goos == "darwin" && goarch == "amd64" {
+		return "nvim-macos-x86_64.tar.gz"
+	}
+
+	if goos == "darwin" && goarch == "arm64" {
+		return "nvim-macos-arm64.tar.gz"
+	}
+
+	if goos == "linux" && goarch == "amd64" {
+		if info.Version().Less(linuxX86_64TarballVersion) {
+			return "nvim-linux64.tar.gz"
+		}
+		return "nvim-linux-x86_64.tar.gz"
+	}
+
+	if goos == "linux" && goarch == "arm64" {
+		return "nvim-linux-arm64.tar.gz"
+	}
+
+	return ""
+}
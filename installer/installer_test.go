@@ -0,0 +1,333 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/candango/nvimm/internal/githubtest"
+	"github.com/candango/nvimm/internal/protocol"
+	"github.com/candango/nvimm/release"
+	"github.com/stretchr/testify/assert"
+)
+
+// writerFunc is an io.Writer calling the function.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// roundTripperFunc is an http.RoundTripper calling the function.
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// fixture returns an installer whose client is served by a fake release
+// download server and a release with the linux amd64 asset served by it.
+func fixture(t *testing.T, digest string) (*Installer, *release.Info) {
+	t.Helper()
+	data := githubtest.Tarball("nvim-linux-x86_64", "v0.11.5")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/neovim/neovim/releases/download/v0.11.5/"+
+		"nvim-linux-x86_64.tar.gz", func(w http.ResponseWriter,
+		r *http.Request) {
+		w.Write(data)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	}
+	info := &release.Info{
+		TagName: "v0.11.5",
+		HtmlUrl: srv.URL + "/neovim/neovim/releases/tag/v0.11.5",
+		Assets: []release.Asset{
+			{Name: "nvim-linux-x86_64.tar.gz", Size: float64(len(data)),
+				Digest: digest},
+		},
+	}
+	root := t.TempDir()
+	inst := New(filepath.Join(root, "nvimm"), filepath.Join(root, "cache"))
+	inst.Client = srv.Client()
+	inst.OS, inst.Arch = "linux", "amd64"
+	inst.Now = func() time.Time {
+		return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	}
+	return inst, info
+}
+
+func TestInstaller(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should install a release step by step", func(t *testing.T) {
+		inst, info := fixture(t, "")
+		asset, err := inst.Asset(info)
+		assert.NoError(t, err)
+		progress := &bytes.Buffer{}
+		file, err := inst.Download(ctx, info, asset, progress)
+		assert.NoError(t, err)
+		stored, err := inst.store().Path(asset.Digest, asset.Name)
+		assert.NoError(t, err)
+		assert.Equal(t, stored, file)
+		assert.Equal(t, int(asset.Size), progress.Len())
+
+		fingerprint, err := inst.Verify(file, asset)
+		assert.NoError(t, err)
+		assert.Equal(t, asset.Digest, fingerprint)
+
+		extracted, err := inst.Extract(ctx, file)
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(extracted, "bin", "nvim"))
+
+		record, err := inst.Place(info, asset, extracted)
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(inst.Path("0.11.5"), "bin", "nvim"))
+		assert.Equal(t, inst.Now(), record.InstalledAt)
+		assert.NoDirExists(t, filepath.Dir(extracted))
+		written, err := release.ReadInstallRecord(inst.Path("0.11.5"))
+		assert.NoError(t, err)
+		assert.Equal(t, record.Digest, written.Digest)
+
+		assert.NoError(t, inst.Activate("0.11.5"))
+		current, err := inst.Current()
+		assert.NoError(t, err)
+		assert.Equal(t, "0.11.5", current)
+	})
+
+	t.Run("should install in one call", func(t *testing.T) {
+		inst, info := fixture(t, "")
+		record, err := inst.Install(ctx, info, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "nvim-linux-x86_64.tar.gz", record.Asset)
+		current, err := inst.Current()
+		assert.NoError(t, err)
+		assert.Empty(t, current)
+	})
+
+	t.Run("should report verification problems", func(t *testing.T) {
+		inst, info := fixture(t, "sha256:0000")
+		_, err := inst.Install(ctx, info, nil)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		assert.NoDirExists(t, inst.Path("0.11.5"))
+
+		info.Assets[0].Digest = ""
+		asset, err := inst.Asset(info)
+		assert.NoError(t, err)
+		_, err = inst.Verify(filepath.Join(inst.CachePath, asset.Name), asset)
+		assert.ErrorIs(t, err, ErrNoChecksum)
+		_, err = inst.Install(ctx, info, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should fail without an asset for the platform", func(t *testing.T) {
+		inst, info := fixture(t, "")
+		inst.OS = "windows"
+		_, err := inst.Install(ctx, info, nil)
+		assert.ErrorIs(t, err, ErrNoAsset)
+	})
+
+	t.Run("should fail on download errors", func(t *testing.T) {
+		inst, info := fixture(t, "")
+		info.TagName = "v0.11.4"
+		info.HtmlUrl = info.HtmlUrl[:len(info.HtmlUrl)-1] + "4"
+		_, err := inst.Install(ctx, info, nil)
+		assert.ErrorContains(t, err, "404 Not Found")
+	})
+
+	t.Run("should remove interrupted downloads", func(t *testing.T) {
+		inst, _ := fixture(t, "")
+		data, err := os.ReadFile(filepath.Join("..", "internal", "cli",
+			"testdata", "releases.json"))
+		assert.NoError(t, err)
+		srv := githubtest.NewServer(t, data)
+		srv.Fail("v0.11.5", "nvim-linux-x86_64.tar.gz",
+			githubtest.FaultStall)
+		inst.ReleasesUrl = srv.URL + "/repos/neovim/neovim/releases"
+		releases, err := inst.FetchReleases(ctx)
+		assert.NoError(t, err)
+		info, _, err := inst.Resolve(releases, "0.11.5")
+		assert.NoError(t, err)
+		asset, err := inst.Asset(info)
+		assert.NoError(t, err)
+		file := filepath.Join(inst.CachePath, asset.Name)
+		assertEmpty := func() {
+			assert.NoFileExists(t, file+".part")
+			entries, err := inst.store().Entries()
+			assert.NoError(t, err)
+			assert.Empty(t, entries)
+		}
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		_, err = inst.Download(cancelCtx, info, asset, writerFunc(
+			func(p []byte) (int, error) {
+				cancel()
+				return len(p), nil
+			}))
+		assert.ErrorIs(t, err, context.Canceled)
+		assertEmpty()
+
+		inst.Client, err = protocol.NewClient(protocol.ClientOptions{
+			ReadTimeout: 50 * time.Millisecond}, nil)
+		assert.NoError(t, err)
+		_, err = inst.Download(ctx, info, asset, nil)
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+		assertEmpty()
+	})
+
+	t.Run("should retry interrupted downloads", func(t *testing.T) {
+		inst, _ := fixture(t, "")
+		data, err := os.ReadFile(filepath.Join("..", "internal", "cli",
+			"testdata", "releases.json"))
+		assert.NoError(t, err)
+		srv := githubtest.NewServer(t, data)
+		srv.FailTimes("v0.11.5", "nvim-linux-x86_64.tar.gz",
+			githubtest.FaultTruncate, 1)
+		inst.ReleasesUrl = srv.URL + "/repos/neovim/neovim/releases"
+		inst.Retry = RetryPolicy{Retries: 1,
+			BaseBackoff: time.Millisecond}
+		releases, err := inst.FetchReleases(ctx)
+		assert.NoError(t, err)
+		info, _, err := inst.Resolve(releases, "0.11.5")
+		assert.NoError(t, err)
+		asset, err := inst.Asset(info)
+		assert.NoError(t, err)
+
+		progress := &bytes.Buffer{}
+		file, err := inst.Download(ctx, info, asset, progress)
+		assert.NoError(t, err)
+		assert.Equal(t, int(asset.Size), progress.Len())
+		_, err = inst.Verify(file, asset)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, srv.Hits("/neovim/neovim/releases/download/"+
+			"v0.11.5/nvim-linux-x86_64.tar.gz"))
+	})
+
+	t.Run("should remove interrupted extractions", func(t *testing.T) {
+		inst, info := fixture(t, "")
+		asset, err := inst.Asset(info)
+		assert.NoError(t, err)
+		file, err := inst.Download(ctx, info, asset, nil)
+		assert.NoError(t, err)
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = inst.Extract(cancelCtx, file)
+		assert.ErrorIs(t, err, context.Canceled)
+		extracted, err := filepath.Glob(filepath.Join(inst.CachePath,
+			"extract-*"))
+		assert.NoError(t, err)
+		assert.Empty(t, extracted)
+	})
+
+	t.Run("should reuse cached downloads", func(t *testing.T) {
+		inst, info := fixture(t, "")
+		asset, err := inst.Asset(info)
+		assert.NoError(t, err)
+		_, ok := inst.Cached(asset)
+		assert.False(t, ok)
+		file, err := inst.Download(ctx, info, asset, nil)
+		assert.NoError(t, err)
+		cached, ok := inst.Cached(asset)
+		assert.True(t, ok)
+		assert.Equal(t, file, cached)
+
+		inst.Client = &http.Client{Transport: roundTripperFunc(
+			func(r *http.Request) (*http.Response, error) {
+				return nil, errors.New("offline")
+			})}
+		again, err := inst.Download(ctx, info, asset, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, file, again)
+
+		assert.NoError(t, os.WriteFile(file, []byte("corrupted"), 0644))
+		_, ok = inst.Cached(asset)
+		assert.False(t, ok)
+		assert.NoFileExists(t, file)
+		_, err = inst.Download(ctx, info, asset, nil)
+		assert.ErrorContains(t, err, "offline")
+	})
+
+	t.Run("should download again on a checksum mismatch", func(t *testing.T) {
+		inst, info := fixture(t, "")
+		asset, err := inst.Asset(info)
+		assert.NoError(t, err)
+		file, err := inst.Download(ctx, info, asset, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(file, []byte("corrupted"), 0644))
+		verified, fingerprint, redownloaded, err := inst.VerifyOrRedownload(
+			ctx, info, asset, file)
+		assert.NoError(t, err)
+		assert.True(t, redownloaded)
+		assert.Equal(t, file, verified)
+		assert.Equal(t, asset.Digest, fingerprint)
+	})
+
+	t.Run("should fetch and validate the listing", func(t *testing.T) {
+		inst, _ := fixture(t, "")
+		data, err := os.ReadFile(filepath.Join("..", "internal", "cli",
+			"testdata", "releases.json"))
+		assert.NoError(t, err)
+		srv := githubtest.NewServer(t, data)
+		inst.ReleasesUrl = srv.URL + "/repos/neovim/neovim/releases"
+		listing, err := inst.FetchListing(ctx)
+		assert.NoError(t, err)
+		assert.Contains(t, string(listing), `"tag_name":"v0.11.5"`)
+
+		srv.SetReleases([]byte(`{"message": "Bad credentials"}`))
+		_, err = inst.FetchReleases(ctx)
+		assert.ErrorIs(t, err, release.ErrInvalidListing)
+		assert.ErrorContains(t, err, `error payload "Bad credentials"`)
+
+		srv.SetRateLimited(true)
+		_, err = inst.FetchListing(ctx)
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.ErrorContains(t, err, "it resets at "+
+			githubtest.RateLimitReset.Format("15:04:05"))
+	})
+
+	t.Run("should resolve releases", func(t *testing.T) {
+		inst, _ := fixture(t, "")
+		data, err := os.ReadFile(filepath.Join("..", "internal", "cli",
+			"testdata", "releases.json"))
+		assert.NoError(t, err)
+		srv := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Write(data)
+			}))
+		defer srv.Close()
+		inst.ReleasesUrl = srv.URL
+		releases, err := inst.FetchReleases(ctx)
+		assert.NoError(t, err)
+
+		info, res, err := inst.Resolve(releases, "0.10")
+		assert.NoError(t, err)
+		assert.Equal(t, "0.10.4", info.CleanTagName())
+		assert.Equal(t, "0.10", res.Query)
+
+		_, _, err = inst.Resolve(releases, "0.1")
+		assert.ErrorContains(t, err, "release 0.1 does not exists")
+	})
+
+	t.Run("should name the tarball for the platform", func(t *testing.T) {
+		old := &release.Info{TagName: "v0.10.3"}
+		info := &release.Info{TagName: "v0.11.5"}
+		assert.Equal(t, "nvim-linux64.tar.gz", TarballName(old, "linux",
+			"amd64"))
+		assert.Equal(t, "nvim-linux-x86_64.tar.gz", TarballName(info,
+			"linux", "amd64"))
+		assert.Equal(t, "nvim-macos-arm64.tar.gz", TarballName(info,
+			"darwin", "arm64"))
+		assert.Empty(t, TarballName(info, "windows", "amd64"))
+	})
+}
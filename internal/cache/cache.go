@@ -3,19 +3,33 @@
 package cache
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/candango/nvimm/internal/logging"
 )
 
+// ErrNotFound is returned by Cacher.Get when nothing was cached yet.
+var ErrNotFound = errors.New("cache entry not found")
+
 // Cacher defines the behavior for data persistence and expiration logic.
+// Implementations must pass the conformance suite of the cachetest package.
 type Cacher interface {
-	// Get retrieves the cached data as a string.
+	// Get retrieves the cached data as a string. It returns an error
+	// satisfying errors.Is(err, ErrNotFound) if nothing was cached.
 	Get() ([]byte, error)
 	// Set persists the provided string data to the cache.
 	Set(data []byte) error
 	// Expired returns true if the cached data is older than the specified TTL.
 	Expired(ttl time.Duration) bool
+	// Delete removes the cached data. Deleting an empty cache is not an
+	// error.
+	Delete() error
 }
 
 // FileCacher is a filesystem-based implementation of the Cacher interface.
@@ -23,6 +37,9 @@ type Cacher interface {
 type FileCacher struct {
 	// Path is the absolute path to the cache file.
 	Path string
+	// Logger receives debug records about cache hits and writes. It may be
+	// nil.
+	Logger *slog.Logger
 }
 
 // NewFileCacher initializes a FileCacher. It determines the OS-specific user
@@ -37,19 +54,43 @@ func NewFileCacher(dir string, filename string) *FileCacher {
 // It returns an error if the file cannot be read.
 func (fc *FileCacher) Get() ([]byte, error) {
 	data, err := os.ReadFile(fc.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
 	if err != nil {
 		return nil, err
 	}
+	fc.log().Debug("cache read", "path", fc.Path, "bytes", len(data))
 	return data, nil
 }
 
 // Set writes the provided byte slice to the filesystem.
 // It automatically creates the necessary directory tree with 0755 permissions.
+// The data is written to a temporary file renamed over the cache file, so
+// readers never see a partially written cache.
 func (fc *FileCacher) Set(data []byte) error {
-	if err := os.MkdirAll(filepath.Dir(fc.Path), 0755); err != nil {
+	dir := filepath.Dir(fc.Path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	fc.log().Debug("cache write", "path", fc.Path, "bytes", len(data))
+	tmp, err := os.CreateTemp(dir, filepath.Base(fc.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
 		return err
 	}
-	return os.WriteFile(fc.Path, data, 0644)
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fc.Path)
 }
 
 // Expired checks the file modification time against the current time.
@@ -58,7 +99,32 @@ func (fc *FileCacher) Set(data []byte) error {
 func (fc *FileCacher) Expired(ttl time.Duration) bool {
 	info, err := os.Stat(fc.Path)
 	if err != nil {
+		fc.log().Debug("cache miss", "path", fc.Path)
 		return true
 	}
-	return time.Since(info.ModTime()) > ttl
+	age := time.Since(info.ModTime())
+	fc.log().Debug("cache age", "path", fc.Path, "age", age, "ttl", ttl)
+	return age > ttl
+}
+
+// Delete removes the cache file.
+func (fc *FileCacher) Delete() error {
+	fc.log().Debug("cache delete", "path", fc.Path)
+	err := os.Remove(fc.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// String returns the path of the cache file.
+func (fc *FileCacher) String() string {
+	return fc.Path
+}
+
+func (fc *FileCacher) log() *slog.Logger {
+	if fc.Logger == nil {
+		return logging.Discard()
+	}
+	return fc.Logger
 }
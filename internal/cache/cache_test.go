@@ -1,6 +1,9 @@
 package cache
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,4 +30,21 @@ func TestFileCacher(t *testing.T) {
 
 		assert.Equal(t, expected, got)
 	})
+
+	t.Run("should replace the cache file atomically", func(t *testing.T) {
+		assert.NoError(t, c.Set([]byte(`[{"tag_name": "v0.11.4"}]`)))
+		assert.NoError(t, c.Set([]byte(`[]`)))
+		got, err := c.Get()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(`[]`), got)
+
+		files, err := filepath.Glob(filepath.Join(tmpDir, "*"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{c.Path}, files)
+		fi, err := os.Stat(c.Path)
+		assert.NoError(t, err)
+		if runtime.GOOS != "windows" {
+			assert.Equal(t, os.FileMode(0644), fi.Mode().Perm())
+		}
+	})
 }
@@ -0,0 +1,126 @@
+// Package cachetest provides the conformance suite every cache.Cacher
+// implementation must pass.
+package cachetest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/candango/nvimm/internal/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// Run runs the conformance suite against the cachers returned by newCacher,
+// which must return an empty cacher, not shared with other calls, on every
+// call.
+func Run(t *testing.T, newCacher func(t *testing.T) cache.Cacher) {
+
+	t.Run("should be empty and expired before set", func(t *testing.T) {
+		c := newCacher(t)
+		_, err := c.Get()
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+		assert.True(t, c.Expired(time.Hour))
+		assert.NoError(t, c.Delete())
+	})
+
+	t.Run("should set, replace and get data", func(t *testing.T) {
+		c := newCacher(t)
+		assert.NoError(t, c.Set([]byte(`[{"tag_name": "v0.11.4"}]`)))
+		assert.NoError(t, c.Set([]byte(`[{"tag_name": "v0.11.5"}]`)))
+		got, err := c.Get()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(`[{"tag_name": "v0.11.5"}]`), got)
+		assert.False(t, c.Expired(time.Hour))
+
+		assert.NoError(t, c.Set([]byte{}))
+		got, err = c.Get()
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("should not share the data slices", func(t *testing.T) {
+		c := newCacher(t)
+		data := []byte("nightly")
+		assert.NoError(t, c.Set(data))
+		data[0] = 'N'
+		got, err := c.Get()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("nightly"), got)
+		got[0] = 'N'
+		got, err = c.Get()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("nightly"), got)
+	})
+
+	t.Run("should expire after the ttl", func(t *testing.T) {
+		c := newCacher(t)
+		assert.NoError(t, c.Set([]byte("stable")))
+		time.Sleep(20 * time.Millisecond)
+		assert.True(t, c.Expired(10*time.Millisecond))
+		assert.False(t, c.Expired(time.Hour))
+		assert.NoError(t, c.Set([]byte("stable")))
+		assert.False(t, c.Expired(10*time.Millisecond))
+	})
+
+	t.Run("should delete the data", func(t *testing.T) {
+		c := newCacher(t)
+		assert.NoError(t, c.Set([]byte("stable")))
+		assert.NoError(t, c.Delete())
+		_, err := c.Get()
+		assert.ErrorIs(t, err, cache.ErrNotFound)
+		assert.True(t, c.Expired(time.Hour))
+		assert.NoError(t, c.Delete())
+	})
+
+	t.Run("should never return partial writes", func(t *testing.T) {
+		c := newCacher(t)
+		values := [][]byte{}
+		for i := 0; i < 4; i++ {
+			values = append(values, bytes.Repeat([]byte{byte('a' + i)},
+				64*1024))
+		}
+		assert.NoError(t, c.Set(values[0]))
+		var wg sync.WaitGroup
+		errs := make(chan error, 64)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					if i%2 == 0 {
+						if err := c.Set(values[(i+j)%len(values)]); err != nil {
+							errs <- err
+						}
+						continue
+					}
+					got, err := c.Get()
+					if err != nil {
+						errs <- err
+						continue
+					}
+					if !containsValue(values, got) {
+						errs <- fmt.Errorf("partial write read, %d bytes",
+							len(got))
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	})
+}
+
+func containsValue(values [][]byte, value []byte) bool {
+	for _, v := range values {
+		if bytes.Equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/candango/nvimm/internal/cache"
+	"github.com/candango/nvimm/internal/cache/cachetest"
+	"github.com/candango/nvimm/internal/cache/redistest"
+)
+
+func TestFileCacherConformance(t *testing.T) {
+	cachetest.Run(t, func(t *testing.T) cache.Cacher {
+		return cache.NewFileCacher(t.TempDir(), "nvimm_releases.json")
+	})
+}
+
+func TestMemoryCacherConformance(t *testing.T) {
+	cachetest.Run(t, func(t *testing.T) cache.Cacher {
+		return cache.NewMemoryCacher()
+	})
+}
+
+// TestRedisCacherConformance runs against an in-process fake Redis, or
+// against the Redis server at NVIMM_TEST_REDIS_URL when set, like
+// redis://localhost:6379/15. The keys used are removed afterwards.
+func TestRedisCacherConformance(t *testing.T) {
+	url := os.Getenv("NVIMM_TEST_REDIS_URL")
+	if url == "" {
+		url = redistest.NewServer(t).URL()
+	}
+	client, err := cache.NewRedisClient(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("redis at %s is not reachable: %v", url, err)
+	}
+	cachetest.Run(t, func(t *testing.T) cache.Cacher {
+		key := fmt.Sprintf("nvimm:test:%s:%d", t.Name(),
+			time.Now().UnixNano())
+		t.Cleanup(func() { client.Del(context.Background(), key) })
+		return cache.NewRedisCacher(client, key)
+	})
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// MemoryCacher is an in-memory implementation of the Cacher interface, safe
+// for concurrent use. The data lives as long as the MemoryCacher, which makes
+// it suited to tests and to tools embedding nvimm that keep their own
+// lifecycle.
+type MemoryCacher struct {
+	mu      sync.RWMutex
+	data    []byte
+	updated time.Time
+	set     bool
+}
+
+// NewMemoryCacher initializes an empty MemoryCacher.
+func NewMemoryCacher() *MemoryCacher {
+	return &MemoryCacher{}
+}
+
+// Get returns a copy of the cached data.
+func (mc *MemoryCacher) Get() ([]byte, error) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	if !mc.set {
+		return nil, ErrNotFound
+	}
+	return bytes.Clone(mc.data), nil
+}
+
+// Set keeps a copy of the data, so changing the slice afterwards does not
+// change the cache.
+func (mc *MemoryCacher) Set(data []byte) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.data = bytes.Clone(data)
+	mc.updated = time.Now()
+	mc.set = true
+	return nil
+}
+
+// Expired returns true if nothing was cached or the data was set longer than
+// the TTL ago.
+func (mc *MemoryCacher) Expired(ttl time.Duration) bool {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return !mc.set || time.Since(mc.updated) > ttl
+}
+
+// Delete forgets the cached data.
+func (mc *MemoryCacher) Delete() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.data, mc.updated, mc.set = nil, time.Time{}, false
+	return nil
+}
+
+// String describes the cache.
+func (mc *MemoryCacher) String() string {
+	return "memory"
+}
@@ -0,0 +1,169 @@
+// Package redistest provides a fake Redis server for hermetic tests. It
+// speaks the RESP2 protocol over TCP and keeps hashes in memory, supporting
+// the commands used by the redis cache backend.
+package redistest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server is a fake Redis listening at Addr.
+type Server struct {
+	// Addr is the host and port the server listens at.
+	Addr     string
+	listener net.Listener
+	mu       sync.Mutex
+	hashes   map[string]map[string]string
+}
+
+// NewServer starts a server on a random local port, stopped when the test
+// finishes.
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start the fake redis: %v", err)
+	}
+	srv := &Server{
+		Addr:     listener.Addr().String(),
+		listener: listener,
+		hashes:   map[string]map[string]string{},
+	}
+	go srv.serve()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// URL returns the redis URL of the database of the server.
+func (srv *Server) URL() string {
+	return "redis://" + srv.Addr + "/0"
+}
+
+// Close stops accepting connections.
+func (srv *Server) Close() {
+	srv.listener.Close()
+}
+
+func (srv *Server) serve() {
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return
+		}
+		go srv.handle(conn)
+	}
+}
+
+// handle answers the commands sent through the connection until it is
+// closed.
+func (srv *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		srv.exec(w, args)
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// exec writes the reply to the command.
+func (srv *Server) exec(w io.Writer, args []string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	name := strings.ToUpper(args[0])
+	switch {
+	case name == "PING":
+		fmt.Fprint(w, "+PONG\r\n")
+	case name == "CLIENT" || name == "SELECT":
+		fmt.Fprint(w, "+OK\r\n")
+	case name == "HSET" && len(args) >= 4 && len(args)%2 == 0:
+		hash := srv.hashes[args[1]]
+		if hash == nil {
+			hash = map[string]string{}
+			srv.hashes[args[1]] = hash
+		}
+		added := 0
+		for i := 2; i < len(args); i += 2 {
+			if _, ok := hash[args[i]]; !ok {
+				added++
+			}
+			hash[args[i]] = args[i+1]
+		}
+		fmt.Fprintf(w, ":%d\r\n", added)
+	case name == "HGET" && len(args) == 3:
+		value, ok := srv.hashes[args[1]][args[2]]
+		if !ok {
+			fmt.Fprint(w, "$-1\r\n")
+			return
+		}
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(value), value)
+	case name == "DEL" && len(args) >= 2:
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := srv.hashes[key]; ok {
+				delete(srv.hashes, key)
+				deleted++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", deleted)
+	default:
+		// HELLO is refused too, so clients fall back to RESP2.
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	count, err := readLength(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	if count < 1 {
+		return nil, fmt.Errorf("empty command")
+	}
+	args := make([]string, count)
+	for i := range args {
+		size, err := readLength(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// readLength reads a line with the prefix followed by a length.
+func readLength(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) < 2 || line[0] != prefix {
+		return 0, fmt.Errorf("unexpected line %q", line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid length in %q", line)
+	}
+	return n, nil
+}
@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/candango/nvimm/internal/filehash"
+	"github.com/candango/nvimm/internal/logging"
+)
+
+// ErrInvalidDigest is returned when a digest is not formatted as
+// "sha256:<hex>".
+var ErrInvalidDigest = errors.New("invalid digest")
+
+var digestRe = regexp.MustCompile(`^sha256:([a-f0-9]{64})$`)
+
+// Store is a content-addressed store of downloaded files. Every file is kept
+// under a directory named after the hex of its sha256 digest, with its
+// original name, so files with the same name and different contents never
+// overwrite each other and a file is found again by its digest.
+type Store struct {
+	// Dir is the root directory of the store.
+	Dir string
+	// Logger receives debug records about hits and misses. It may be nil.
+	Logger *slog.Logger
+}
+
+// Entry is a file kept in the store.
+type Entry struct {
+	Digest  string
+	Name    string
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// NewStore creates a Store rooted at dir.
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// Path returns where the file named name with the digest is kept.
+func (s *Store) Path(digest string, name string) (string, error) {
+	m := digestRe.FindStringSubmatch(digest)
+	if m == nil {
+		return "", fmt.Errorf("%w %q, expected sha256:<hex>",
+			ErrInvalidDigest, digest)
+	}
+	return filepath.Join(s.Dir, m[1], filepath.Base(name)), nil
+}
+
+// Lookup returns the path of the file named name with the digest if it is
+// kept and its contents still match the digest. A file whose contents no
+// longer match is removed.
+func (s *Store) Lookup(digest string, name string) (string, bool) {
+	path, err := s.Path(digest, name)
+	if err != nil {
+		return "", false
+	}
+	actual, err := filehash.SHA256(path)
+	if err != nil {
+		s.log().Debug("store miss", "digest", digest, "name", name)
+		return "", false
+	}
+	if actual != digest {
+		s.log().Warn("store entry corrupted, removing it", "path", path,
+			"expected", digest, "actual", actual)
+		os.RemoveAll(filepath.Dir(path))
+		return "", false
+	}
+	s.log().Debug("store hit", "digest", digest, "path", path)
+	return path, true
+}
+
+// Put moves the file into the store under its digest, keeping the name, and
+// returns its new path and its digest.
+func (s *Store) Put(file string, name string) (string, string, error) {
+	digest, err := filehash.SHA256(file)
+	if err != nil {
+		return "", "", fmt.Errorf("checksum calculation failed: %w", err)
+	}
+	path, err := s.Path(digest, name)
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", "", err
+	}
+	if err := os.Rename(file, path); err != nil {
+		return "", "", err
+	}
+	s.log().Debug("store put", "digest", digest, "path", path)
+	return path, digest, nil
+}
+
+// Entries returns the files kept in the store, sorted by name and then by
+// modification time, newest first.
+func (s *Store) Entries() ([]Entry, error) {
+	dirs, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []Entry{}
+	for _, dir := range dirs {
+		digest := "sha256:" + dir.Name()
+		if !dir.IsDir() || !digestRe.MatchString(digest) {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(s.Dir, dir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			fi, err := file.Info()
+			if err != nil || !fi.Mode().IsRegular() {
+				continue
+			}
+			entries = append(entries, Entry{
+				Digest:  digest,
+				Name:    file.Name(),
+				Path:    filepath.Join(s.Dir, dir.Name(), file.Name()),
+				Size:    fi.Size(),
+				ModTime: fi.ModTime(),
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].ModTime.After(entries[j].ModTime)
+	})
+	return entries, nil
+}
+
+// Verify recomputes the digest of the entry and returns it, with an error if
+// it does not match the digest the entry is kept under.
+func (s *Store) Verify(entry Entry) (string, error) {
+	actual, err := filehash.SHA256(entry.Path)
+	if err != nil {
+		return "", err
+	}
+	if actual != entry.Digest {
+		return actual, fmt.Errorf("%s is corrupted, expected %s but got %s",
+			entry.Path, entry.Digest, actual)
+	}
+	return actual, nil
+}
+
+// Remove removes the entry from the store.
+func (s *Store) Remove(entry Entry) error {
+	if !strings.HasPrefix(entry.Path, s.Dir+string(filepath.Separator)) {
+		return fmt.Errorf("%s is not in the store %s", entry.Path, s.Dir)
+	}
+	return os.RemoveAll(filepath.Dir(entry.Path))
+}
+
+func (s *Store) log() *slog.Logger {
+	if s.Logger == nil {
+		return logging.Discard()
+	}
+	return s.Logger
+}
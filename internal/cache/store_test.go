@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	// put writes the data to a temporary file and puts it into the store.
+	put := func(t *testing.T, s *Store, name string, data string) (string,
+		string) {
+		t.Helper()
+		file := filepath.Join(t.TempDir(), name+".part")
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		path, digest, err := s.Put(file, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path, digest
+	}
+
+	t.Run("should keep files by digest", func(t *testing.T) {
+		s := NewStore(t.TempDir())
+		path, digest := put(t, s, "nvim-linux-x86_64.tar.gz", "0.11.5")
+		assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256(
+			[]byte("0.11.5"))), digest)
+		assert.Equal(t, filepath.Join(s.Dir, digest[7:],
+			"nvim-linux-x86_64.tar.gz"), path)
+		older, olderDigest := put(t, s, "nvim-linux-x86_64.tar.gz", "0.11.4")
+		assert.NotEqual(t, path, older)
+
+		found, ok := s.Lookup(digest, "nvim-linux-x86_64.tar.gz")
+		assert.True(t, ok)
+		assert.Equal(t, path, found)
+		found, ok = s.Lookup(olderDigest, "nvim-linux-x86_64.tar.gz")
+		assert.True(t, ok)
+		assert.Equal(t, older, found)
+		data, err := os.ReadFile(found)
+		assert.NoError(t, err)
+		assert.Equal(t, "0.11.4", string(data))
+
+		_, ok = s.Lookup(digest, "nvim-macos-arm64.tar.gz")
+		assert.False(t, ok)
+		_, ok = s.Lookup("md5:abc", "nvim-linux-x86_64.tar.gz")
+		assert.False(t, ok)
+		_, err = s.Path("sha256:xyz", "nvim-linux-x86_64.tar.gz")
+		assert.ErrorIs(t, err, ErrInvalidDigest)
+	})
+
+	t.Run("should drop corrupted files", func(t *testing.T) {
+		s := NewStore(t.TempDir())
+		path, digest := put(t, s, "nvim-linux-x86_64.tar.gz", "0.11.5")
+		assert.NoError(t, os.WriteFile(path, []byte("0.11"), 0644))
+		entries, err := s.Entries()
+		assert.NoError(t, err)
+		actual, err := s.Verify(entries[0])
+		assert.ErrorContains(t, err, "is corrupted")
+		assert.NotEqual(t, digest, actual)
+
+		_, ok := s.Lookup(digest, "nvim-linux-x86_64.tar.gz")
+		assert.False(t, ok)
+		assert.NoDirExists(t, filepath.Dir(path))
+	})
+
+	t.Run("should list and remove entries", func(t *testing.T) {
+		s := NewStore(filepath.Join(t.TempDir(), "downloads"))
+		entries, err := s.Entries()
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+
+		macos, _ := put(t, s, "nvim-macos-arm64.tar.gz", "macos")
+		older, _ := put(t, s, "nvim-linux-x86_64.tar.gz", "0.11.4")
+		newer, _ := put(t, s, "nvim-linux-x86_64.tar.gz", "0.11.5")
+		yesterday := time.Now().Add(-24 * time.Hour)
+		assert.NoError(t, os.Chtimes(older, yesterday, yesterday))
+		assert.NoError(t, os.MkdirAll(filepath.Join(s.Dir, "not-a-digest"),
+			0755))
+
+		entries, err = s.Entries()
+		assert.NoError(t, err)
+		paths := []string{}
+		for _, entry := range entries {
+			paths = append(paths, entry.Path)
+			_, err := s.Verify(entry)
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, []string{newer, older, macos}, paths)
+		assert.Equal(t, int64(6), entries[0].Size)
+
+		assert.NoError(t, s.Remove(entries[0]))
+		assert.NoFileExists(t, newer)
+		assert.Error(t, s.Remove(Entry{Path: "/etc/passwd"}))
+	})
+}
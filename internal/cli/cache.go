@@ -0,0 +1,356 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/candango/nvimm/installer"
+	"github.com/candango/nvimm/internal/cache"
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/internal/ui"
+	"github.com/candango/nvimm/release"
+)
+
+// ErrCacheCorrupted is returned by the cache verify command when a cached
+// download does not match its digest.
+var ErrCacheCorrupted = errors.New("corrupted cached downloads found")
+
+// CacheCommand groups the commands managing the downloads cache.
+type CacheCommand struct {
+	List   CacheListCommand   `command:"list" description:"List the cached downloads"`
+	Clean  CacheCleanCommand  `command:"clean" description:"Remove the cached downloads"`
+	Size   CacheSizeCommand   `command:"size" description:"Show the disk space used by the cache"`
+	Verify CacheVerifyCommand `command:"verify" description:"Check the cached downloads against their digests"`
+}
+
+// CacheEntryView is the schema used to render a cached download in
+// structured outputs.
+type CacheEntryView struct {
+	Asset        string    `json:"asset" yaml:"asset"`
+	Digest       string    `json:"digest" yaml:"digest"`
+	Size         int64     `json:"size" yaml:"size"`
+	Path         string    `json:"path" yaml:"path"`
+	DownloadedAt time.Time `json:"downloaded_at" yaml:"downloaded_at"`
+	// InstalledBy lists the installed releases whose install record has the
+	// digest of the download.
+	InstalledBy []string `json:"installed_by" yaml:"installed_by"`
+	// Status is set by the verify command, ok or corrupted.
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// CacheListView is the result of the cache list and verify commands.
+type CacheListView struct {
+	Downloads []CacheEntryView `json:"downloads" yaml:"downloads"`
+}
+
+// CacheSizeView is the result of the cache size command.
+type CacheSizeView struct {
+	Path          string `json:"path" yaml:"path"`
+	Downloads     int    `json:"downloads" yaml:"downloads"`
+	DownloadsSize int64  `json:"downloads_size" yaml:"downloads_size"`
+	TotalSize     int64  `json:"total_size" yaml:"total_size"`
+}
+
+// CacheCleanView is the result of the cache clean command.
+type CacheCleanView struct {
+	Removed []string `json:"removed" yaml:"removed"`
+	Freed   int64    `json:"freed" yaml:"freed"`
+}
+
+const (
+	CacheStatusOK        = "ok"
+	CacheStatusCorrupted = "corrupted"
+)
+
+// downloadsStore returns the store the installer keeps the downloaded
+// assets in.
+func downloadsStore(appOpts *config.AppOptions) *cache.Store {
+	store := cache.NewStore(filepath.Join(appOpts.CachePath,
+		installer.DownloadsDir))
+	store.Logger = appOpts.Log()
+	return store
+}
+
+// cacheEntries returns the views of the cached downloads, with the installed
+// releases using each of them.
+func cacheEntries(appOpts *config.AppOptions) ([]CacheEntryView, error) {
+	entries, err := downloadsStore(appOpts).Entries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached downloads: %w", err)
+	}
+	installedBy := map[string][]string{}
+	dirs, _ := os.ReadDir(appOpts.Path)
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		record, err := release.ReadInstallRecord(filepath.Join(appOpts.Path,
+			dir.Name()))
+		if err != nil || record.Digest == "" {
+			continue
+		}
+		installedBy[record.Digest] = append(installedBy[record.Digest],
+			dir.Name())
+	}
+	views := []CacheEntryView{}
+	for _, entry := range entries {
+		by := installedBy[entry.Digest]
+		if by == nil {
+			by = []string{}
+		}
+		views = append(views, CacheEntryView{
+			Asset:        entry.Name,
+			Digest:       entry.Digest,
+			Size:         entry.Size,
+			Path:         entry.Path,
+			DownloadedAt: entry.ModTime,
+			InstalledBy:  by,
+		})
+	}
+	return views, nil
+}
+
+// shortDigest returns the first hex characters of the digest, enough to tell
+// the cached downloads apart in tables.
+func shortDigest(digest string) string {
+	hex := strings.TrimPrefix(digest, "sha256:")
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	return hex
+}
+
+// writeCacheTable writes the cached downloads in the human readable format,
+// with their verification status when verified.
+func writeCacheTable(w io.Writer, view CacheListView, verified bool) error {
+	if len(view.Downloads) == 0 {
+		_, err := fmt.Fprintln(w, "No cached downloads.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if verified {
+		fmt.Fprintln(tw, "Asset\tDigest\tStatus")
+	} else {
+		fmt.Fprintln(tw, "Asset\tDigest\tSize\tDownloaded\tInstalled by")
+	}
+	var total int64
+	for _, entry := range view.Downloads {
+		total += entry.Size
+		if verified {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", entry.Asset,
+				shortDigest(entry.Digest), entry.Status)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", entry.Asset,
+			shortDigest(entry.Digest), ui.HumanSize(entry.Size),
+			entry.DownloadedAt.Format(time.DateOnly),
+			orDash(strings.Join(entry.InstalledBy, ", ")))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if verified {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "%d downloads, %s.\n", len(view.Downloads),
+		ui.HumanSize(total))
+	return err
+}
+
+type CacheListCommand struct {
+	appOpts *config.AppOptions
+}
+
+func (cmd *CacheListCommand) Execute(args []string) error {
+	entries, err := cacheEntries(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	view := CacheListView{Downloads: entries}
+	return NewPrinter(cmd.appOpts).Render(view, func(w io.Writer) error {
+		return writeCacheTable(w, view, false)
+	})
+}
+
+func (cmd *CacheListCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+type CacheSizeCommand struct {
+	appOpts *config.AppOptions
+}
+
+func (cmd *CacheSizeCommand) Execute(args []string) error {
+	entries, err := cacheEntries(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	view := CacheSizeView{Path: cmd.appOpts.CachePath,
+		Downloads: len(entries)}
+	for _, entry := range entries {
+		view.DownloadsSize += entry.Size
+	}
+	view.TotalSize, err = diskUsage(cmd.appOpts.CachePath)
+	if err != nil {
+		return fmt.Errorf("failed to measure the cache: %w", err)
+	}
+	return NewPrinter(cmd.appOpts).Render(view, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "%s: %s, %d downloads using %s.\n",
+			view.Path, ui.HumanSize(view.TotalSize), view.Downloads,
+			ui.HumanSize(view.DownloadsSize))
+		return err
+	})
+}
+
+// diskUsage returns the size of the regular files under the path.
+func diskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry,
+		err error) error {
+		if err != nil {
+			return err
+		}
+		if fi, err := d.Info(); err == nil && fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return size, err
+}
+
+func (cmd *CacheSizeCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+type CacheCleanCommand struct {
+	Unused  bool `long:"unused" description:"Only remove downloads not used by an installed release"`
+	appOpts *config.AppOptions
+}
+
+func (cmd *CacheCleanCommand) Execute(args []string) error {
+	unlock, err := lockPaths(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := cacheEntries(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	store := downloadsStore(cmd.appOpts)
+	view := CacheCleanView{Removed: []string{}}
+	for _, entry := range entries {
+		if cmd.Unused && len(entry.InstalledBy) > 0 {
+			continue
+		}
+		if err := store.Remove(cache.Entry{Path: entry.Path}); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+		view.Removed = append(view.Removed, entry.Path)
+		view.Freed += entry.Size
+	}
+	leftovers, err := cacheLeftovers(cmd.appOpts.CachePath)
+	if err != nil {
+		return err
+	}
+	for _, path := range leftovers {
+		size, _ := diskUsage(path)
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		view.Removed = append(view.Removed, path)
+		view.Freed += size
+	}
+	return NewPrinter(cmd.appOpts).Render(view, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "Removed %d files, %s freed.\n",
+			len(view.Removed), ui.HumanSize(view.Freed))
+		return err
+	})
+}
+
+// cacheLeftovers returns the files left in the cache by interrupted
+// downloads and extractions, and by the layout used before downloads were
+// kept by digest, which had tarballs and their extracted releases directly
+// under the cache path. The temporary files of releases cache writes are not
+// returned, as they are written without the lock by other nvimm processes.
+func cacheLeftovers(cachePath string) ([]string, error) {
+	leftovers := []string{}
+	for _, pattern := range []string{"*.part", "extract-*", "nvim-*"} {
+		matches, err := filepath.Glob(filepath.Join(cachePath, pattern))
+		if err != nil {
+			return nil, err
+		}
+		leftovers = append(leftovers, matches...)
+	}
+	return leftovers, nil
+}
+
+func (cmd *CacheCleanCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+type CacheVerifyCommand struct {
+	Remove  bool `long:"remove" description:"Remove the corrupted downloads"`
+	appOpts *config.AppOptions
+}
+
+func (cmd *CacheVerifyCommand) Execute(args []string) error {
+	if cmd.Remove {
+		unlock, err := lockPaths(cmd.appOpts)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	entries, err := cacheEntries(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	store := downloadsStore(cmd.appOpts)
+	corrupted := 0
+	for i, entry := range entries {
+		_, err := store.Verify(cache.Entry{Digest: entry.Digest,
+			Path: entry.Path})
+		entries[i].Status = CacheStatusOK
+		if err == nil {
+			continue
+		}
+		cmd.appOpts.Log().Warn("cached download corrupted", "path",
+			entry.Path, "error", err)
+		entries[i].Status = CacheStatusCorrupted
+		corrupted++
+		if cmd.Remove {
+			if err := store.Remove(cache.Entry{Path: entry.Path}); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+			}
+			entries[i].Status = CacheStatusCorrupted + ", removed"
+		}
+	}
+	view := CacheListView{Downloads: entries}
+	err = NewPrinter(cmd.appOpts).Render(view, func(w io.Writer) error {
+		return writeCacheTable(w, view, true)
+	})
+	if err != nil {
+		return err
+	}
+	if corrupted > 0 && !cmd.Remove {
+		return fmt.Errorf("%w: %d of %d downloads do not match their "+
+			"digest, remove them with --remove", ErrCacheCorrupted,
+			corrupted, len(entries))
+	}
+	return nil
+}
+
+func (cmd *CacheVerifyCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
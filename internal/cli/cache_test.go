@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/release"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	// setup puts two downloads in the cache, the first one used by the
+	// installed 0.11.5, plus leftovers of interrupted and older installs.
+	setup := func(t *testing.T) (*config.AppOptions, []string) {
+		opts := fixtureSetup(t, []string{"0.11.5"}, nil)
+		opts.Output = OutputJSON
+		store := downloadsStore(opts)
+		paths := []string{}
+		for _, data := range []string{"0.11.5", "0.11.4"} {
+			file := filepath.Join(opts.CachePath, "nvim-linux-x86_64.tar.gz.part")
+			assert.NoError(t, os.WriteFile(file, []byte(data), 0644))
+			path, digest, err := store.Put(file, "nvim-linux-x86_64.tar.gz")
+			assert.NoError(t, err)
+			paths = append(paths, path)
+			if data == "0.11.5" {
+				record := &release.InstallRecord{TagName: "v0.11.5",
+					Asset: "nvim-linux-x86_64.tar.gz", Digest: digest}
+				assert.NoError(t, record.Write(filepath.Join(opts.Path,
+					"0.11.5")))
+			}
+		}
+		assert.NoError(t, os.WriteFile(filepath.Join(opts.CachePath,
+			"nvim-macos-arm64.tar.gz.part"), []byte("part"), 0644))
+		assert.NoError(t, os.MkdirAll(filepath.Join(opts.CachePath,
+			"extract-123", "nvim-linux-x86_64"), 0755))
+		assert.NoError(t, os.MkdirAll(filepath.Join(opts.CachePath,
+			"nvim-linux-x86_64"), 0755))
+		// A releases cache write of another nvimm, which is not locked.
+		assert.NoError(t, os.WriteFile(filepath.Join(opts.CachePath,
+			ReleasesCacheName+".123.tmp"), []byte("[]"), 0644))
+		return opts, paths
+	}
+
+	t.Run("should list the downloads with their releases", func(t *testing.T) {
+		opts, paths := setup(t)
+		entries, err := cacheEntries(opts)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		installed := map[string][]string{}
+		for _, entry := range entries {
+			installed[entry.Path] = entry.InstalledBy
+			assert.Equal(t, "nvim-linux-x86_64.tar.gz", entry.Asset)
+			assert.Equal(t, int64(6), entry.Size)
+		}
+		assert.Equal(t, []string{"0.11.5"}, installed[paths[0]])
+		assert.Empty(t, installed[paths[1]])
+		assert.Equal(t, "0123456789ab", shortDigest("sha256:0123456789abcdef"))
+	})
+
+	t.Run("should clean unused downloads and leftovers", func(t *testing.T) {
+		opts, paths := setup(t)
+		cmd := &CacheCleanCommand{Unused: true, appOpts: opts}
+		assert.NoError(t, cmd.Execute(nil))
+		assert.FileExists(t, paths[0])
+		assert.NoFileExists(t, paths[1])
+		leftovers, err := cacheLeftovers(opts.CachePath)
+		assert.NoError(t, err)
+		assert.Empty(t, leftovers)
+		assert.FileExists(t, filepath.Join(opts.CachePath,
+			ReleasesCacheName+".123.tmp"))
+
+		cmd.Unused = false
+		assert.NoError(t, cmd.Execute(nil))
+		entries, err := cacheEntries(opts)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+		assert.DirExists(t, filepath.Join(opts.CachePath, "downloads"))
+	})
+
+	t.Run("should report corrupted downloads", func(t *testing.T) {
+		opts, paths := setup(t)
+		cmd := &CacheVerifyCommand{appOpts: opts}
+		assert.NoError(t, cmd.Execute(nil))
+
+		assert.NoError(t, os.WriteFile(paths[1], []byte("0.11"), 0644))
+		err := cmd.Execute(nil)
+		assert.ErrorIs(t, err, ErrCacheCorrupted)
+		assert.ErrorContains(t, err, "1 of 2 downloads")
+		assert.FileExists(t, paths[1])
+
+		cmd.Remove = true
+		assert.NoError(t, cmd.Execute(nil))
+		assert.NoFileExists(t, paths[1])
+		assert.FileExists(t, paths[0])
+	})
+}
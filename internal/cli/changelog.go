@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/release"
+)
+
+// skippedSectionRe matches release notes sections that describe how to
+// install a release rather than what changed.
+var skippedSectionRe = regexp.MustCompile(`(?i)^(install|checksums?|sha256)`)
+
+type ChangelogCommand struct {
+	Breaking bool `long:"breaking" description:"Show only breaking changes and deprecations"`
+	NoPager  bool `long:"no-pager" description:"Do not page the output"`
+	appOpts  *config.AppOptions
+}
+
+func (cmd *ChangelogCommand) Usage() string {
+	return "<from> [to]"
+}
+
+func (cmd *ChangelogCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("positional argument from was not informed\n")
+	}
+	releases, err := loadReleases(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	inst := newInstaller(cmd.appOpts)
+	from, _, err := inst.Resolve(releases, args[0])
+	if err != nil {
+		return err
+	}
+	query := release.AliasLatest
+	if len(args) > 1 {
+		query = args[1]
+	}
+	to, _, err := inst.Resolve(releases, query)
+	if err != nil {
+		return err
+	}
+	if !from.Version().Less(to.Version()) {
+		return fmt.Errorf("release %s is not older than %s", from.TagName,
+			to.TagName)
+	}
+
+	view := newChangelogView(releases, from, to, cmd.Breaking)
+	p := NewPrinter(cmd.appOpts)
+	table := func(w io.Writer) error {
+		return writeChangelogTable(w, view)
+	}
+	if !p.Structured() && !cmd.NoPager {
+		return page(p.Out, table)
+	}
+	return p.Render(view, table)
+}
+
+func (cmd *ChangelogCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+// ChangelogEntry holds the release notes of one release in a changelog.
+type ChangelogEntry struct {
+	Tag          string        `json:"tag" yaml:"tag"`
+	Version      string        `json:"version" yaml:"version"`
+	PublishedAt  time.Time     `json:"published_at" yaml:"published_at"`
+	Url          string        `json:"url,omitempty" yaml:"url,omitempty"`
+	Breaking     bool          `json:"breaking" yaml:"breaking"`
+	Deprecations bool          `json:"deprecations" yaml:"deprecations"`
+	Sections     []NoteSection `json:"sections" yaml:"sections"`
+}
+
+// ChangelogView is the result of the changelog command, releases are sorted
+// from the newest to the oldest.
+type ChangelogView struct {
+	From     string           `json:"from" yaml:"from"`
+	To       string           `json:"to" yaml:"to"`
+	Releases []ChangelogEntry `json:"releases" yaml:"releases"`
+}
+
+// newChangelogView collects the release notes of the releases newer than
+// from up to and including to. Installation instructions are left out and,
+// if breaking is true, only breaking changes and deprecations are kept.
+func newChangelogView(releases release.Releases, from *release.Info,
+	to *release.Info, breaking bool) ChangelogView {
+	view := ChangelogView{
+		From:     from.TagName,
+		To:       to.TagName,
+		Releases: []ChangelogEntry{},
+	}
+	for _, info := range releases {
+		v := info.Version()
+		if !from.Version().Less(v) || to.Version().Less(v) {
+			continue
+		}
+		entry := ChangelogEntry{
+			Tag:         info.TagName,
+			Version:     info.CleanTagName(),
+			PublishedAt: info.PublishedAt,
+			Url:         info.HtmlUrl,
+			Sections:    []NoteSection{},
+		}
+		for _, section := range splitSections(info.Body) {
+			if skippedSectionRe.MatchString(section.Title) {
+				continue
+			}
+			if breaking && section.Kind == "" {
+				continue
+			}
+			switch section.Kind {
+			case SectionBreaking:
+				entry.Breaking = true
+			case SectionDeprecation:
+				entry.Deprecations = true
+			}
+			entry.Sections = append(entry.Sections, section)
+		}
+		if breaking && len(entry.Sections) == 0 {
+			continue
+		}
+		view.Releases = append(view.Releases, entry)
+	}
+	return view
+}
+
+// writeChangelogTable writes the changelog in the human readable format.
+// Headings of breaking changes and deprecations are prefixed with "!".
+func writeChangelogTable(w io.Writer, view ChangelogView) error {
+	if len(view.Releases) == 0 {
+		fmt.Fprintf(w, "No changes after %s up to %s\n", view.From, view.To)
+		return nil
+	}
+	fmt.Fprintf(w, "Changes after %s up to %s\n\n", view.From, view.To)
+	breaking, deprecations := 0, 0
+	for _, entry := range view.Releases {
+		if entry.Breaking {
+			breaking++
+		}
+		if entry.Deprecations {
+			deprecations++
+		}
+		heading := fmt.Sprintf("%s (%s)", entry.Tag,
+			entry.PublishedAt.Format(time.DateOnly))
+		fmt.Fprintf(w, "%s\n%s\n\n", heading,
+			strings.Repeat("=", len(heading)))
+
+		notes := &strings.Builder{}
+		for _, section := range entry.Sections {
+			if section.Title != "" {
+				marker := ""
+				if section.Kind != "" {
+					marker = "! "
+				}
+				fmt.Fprintf(notes, "%s %s%s\n\n",
+					strings.Repeat("#", max(section.Level, 2)), marker,
+					section.Title)
+			}
+			fmt.Fprintf(notes, "%s\n\n", section.Text)
+		}
+		if notes.Len() == 0 {
+			fmt.Fprint(w, "  No release notes\n\n")
+			continue
+		}
+		if err := renderMarkdown(w, notes.String(), "  "); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(w, "%d releases, %d with breaking changes, %d with "+
+		"deprecations\n", len(view.Releases), breaking, deprecations)
+	return nil
+}
@@ -1,29 +1,30 @@
 package cli
 
 import (
-	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"io/fs"
 	"os"
-	"path"
 	"path/filepath"
 	"runtime"
-	"strings"
+	"text/tabwriter"
 	"time"
 
-	"github.com/candango/iook/archive"
-	"github.com/candango/iook/dir"
 	"github.com/candango/iook/pathx"
+	"github.com/candango/nvimm/installer"
 	"github.com/candango/nvimm/internal/cache"
 	"github.com/candango/nvimm/internal/config"
-	"github.com/candango/nvimm/internal/filehash"
+	"github.com/candango/nvimm/internal/lock"
 	"github.com/candango/nvimm/internal/protocol"
-	"github.com/candango/nvimm/internal/release"
+	"github.com/candango/nvimm/internal/ui"
+	"github.com/candango/nvimm/release"
 )
 
 type CurrentCommand struct {
 	Release string `positional-arg-name:"release" description:"Release version to be set"`
+	Pick    bool   `long:"pick" description:"Pick the release to be set from the installed releases"`
 	appOpts *config.AppOptions
 }
 
@@ -40,45 +41,34 @@ func (cmd *CurrentCommand) Execute(args []string) error {
 		return fmt.Errorf("nvim path does not exist: %s",
 			cmd.appOpts.Path)
 	}
-	// nvimPath := cmd.appOptions.Path
-	cachePath := cmd.appOpts.CachePath
-
-	releaseCacher := cache.NewFileCacher(cachePath, "nvimm_releases.json")
-	gt, err := protocol.NewGithubTransport()
-	if err != nil {
-		return fmt.Errorf("failed to create github transport: %w", err)
-	}
-
-	// TODO: use parametrized expiration time
-	if releaseCacher.Expired(30 * time.Minute) {
-		res, err := gt.GetReleases()
-		if err != nil {
-			return fmt.Errorf("failed to get releases: %w", err)
-		}
-		data, err := io.ReadAll(res.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read response body: %w", err)
-		}
-		err = releaseCacher.Set(data)
-		if err != nil {
-			return fmt.Errorf("failed to cache releases: %w", err)
-		}
-	}
-
-	data, err := releaseCacher.Get()
-	if err != nil {
-		return fmt.Errorf("failed to get cached releases: %w", err)
-	}
-	releases := release.Releases{}
-
-	err = releases.Process(data, cmd.appOpts)
+	p := NewPrinter(cmd.appOpts)
+	releases, err := loadReleases(cmd.appOpts)
 	if err != nil {
-		return fmt.Errorf("failed to process releases: %w", err)
+		return err
 	}
 	notInstalled := len(releases.Installed(cmd.appOpts.Path)) == 0
 	if notInstalled {
 		return fmt.Errorf("no releases installed yet")
 	}
+	currentInstalled, err := currentRelease(cmd.appOpts.Path)
+	if err != nil {
+		return err
+	}
+	if cmd.Pick && len(args) == 0 {
+		installed := []ReleaseView{}
+		for _, rv := range listView(&releases, cmd.appOpts.Path,
+			currentInstalled).Releases {
+			if rv.Installed {
+				installed = append(installed, rv)
+			}
+		}
+		picked, err := pickRelease(cmd.appOpts, "Set current release:",
+			installed)
+		if err != nil {
+			return err
+		}
+		args = []string{picked}
+	}
 	mustSetCurrent := true
 	if len(args) == 0 {
 		mustSetCurrent = false
@@ -90,36 +80,55 @@ func (cmd *CurrentCommand) Execute(args []string) error {
 
 	}
 	if !mustSetCurrent {
-		currentInstalled, err := os.Readlink(filepath.Join(cmd.appOpts.Path, "current"))
-		if err != nil {
-			if !os.IsNotExist(err) {
-				return fmt.Errorf("failed to read current symlink: %w", err)
-			}
-			fmt.Printf("no current version set\n")
-			return nil
-
+		view := CurrentView{}
+		if currentInstalled != "" {
+			current := currentView(&releases, cmd.appOpts.Path,
+				currentInstalled)
+			view.Current = &current
 		}
-		fmt.Printf("* %s\n", filepath.Base(currentInstalled))
-		return nil
+		return p.Render(view, func(w io.Writer) error {
+			return writeCurrentTable(w, view)
+		})
 	}
 
-	currentInstalled, err := os.Readlink(filepath.Join(cmd.appOpts.Path, "current"))
+	unlock, err := lockPaths(cmd.appOpts)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("failed to read current symlink: %w", err)
-		}
+		return err
 	}
-
-	if currentInstalled == filepath.Join(cmd.appOpts.Path, cmd.Release) {
-		fmt.Printf("the release %s is already set as current\n", cmd.Release)
+	defer unlock()
+	if currentInstalled == cmd.Release {
+		p.Statusf("the release %s is already set as current\n", cmd.Release)
+	} else if err := setCurrent(cmd.appOpts.Path, cmd.Release); err != nil {
+		return err
+	}
+	if !p.Structured() {
 		return nil
 	}
+	current := currentView(&releases, cmd.appOpts.Path, cmd.Release)
+	return p.Render(CurrentView{Current: &current}, nil)
+}
 
-	os.RemoveAll(filepath.Join(cmd.appOpts.Path, "current"))
-	os.Symlink(
-		filepath.Join(cmd.appOpts.Path, cmd.Release),
-		filepath.Join(cmd.appOpts.Path, "current"))
-	return nil
+// writeCurrentTable writes the current release in the human readable format.
+func writeCurrentTable(w io.Writer, view CurrentView) error {
+	if view.Current == nil {
+		_, err := fmt.Fprintf(w, "no current version set\n")
+		return err
+	}
+	_, err := fmt.Fprintf(w, "* %s\n", view.Current.Version)
+	return err
+}
+
+// currentView creates the view of the installed release with the given name,
+// which is expected to be set as current.
+func currentView(releases *release.Releases, path string,
+	name string) ReleaseView {
+	for _, info := range *releases {
+		if info.CleanTagName() == name {
+			return newReleaseView(&info, filepath.Join(path, name), true,
+				true)
+		}
+	}
+	return installedView(path, name, true)
 }
 
 func (cmd *CurrentCommand) SetAppOptions(opts *config.AppOptions) {
@@ -132,14 +141,10 @@ type InstallCommand struct {
 }
 
 func (cmd *InstallCommand) Usage() string {
-	return "<release>"
+	return "[release]"
 }
 
 func (cmd *InstallCommand) Execute(args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("positional argument release was not informed\n")
-	}
-	cmd.Release = args[0]
 	if !pathx.Exists(cmd.appOpts.CachePath) {
 		return fmt.Errorf("cache path does not exist: %s",
 			cmd.appOpts.CachePath)
@@ -148,173 +153,370 @@ func (cmd *InstallCommand) Execute(args []string) error {
 		return fmt.Errorf("nvim path does not exist: %s",
 			cmd.appOpts.Path)
 	}
-	// nvimPath := cmd.appOptions.Path
-	cachePath := cmd.appOpts.CachePath
-
-	releaseCacher := cache.NewFileCacher(cachePath, "nvimm_releases.json")
-	gt, err := protocol.NewGithubTransport()
+	p := NewPrinter(cmd.appOpts)
+	releases, err := loadReleases(cmd.appOpts)
 	if err != nil {
-		return fmt.Errorf("failed to create github transport: %w", err)
+		return err
 	}
 
-	// TODO: use parametrized expiration time
-	if releaseCacher.Expired(30 * time.Minute) {
-		res, err := gt.GetReleases()
-		if err != nil {
-			return fmt.Errorf("failed to get releases: %w", err)
+	if len(args) == 0 {
+		available := []ReleaseView{}
+		for _, rv := range listView(&releases, cmd.appOpts.Path,
+			"").Releases {
+			if !rv.Installed {
+				available = append(available, rv)
+			}
 		}
-		data, err := io.ReadAll(res.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read response body: %w", err)
+		if len(available) == 0 {
+			return fmt.Errorf("every release is already installed")
 		}
-		err = releaseCacher.Set(data)
+		picked, err := pickRelease(cmd.appOpts, "Install release:",
+			available)
 		if err != nil {
-			return fmt.Errorf("failed to cache releases: %w", err)
+			return err
 		}
+		args = []string{picked}
 	}
+	cmd.Release = args[0]
 
-	data, err := releaseCacher.Get()
+	inst := newInstaller(cmd.appOpts)
+	steps := newInstallSteps(p)
+	var info *release.Info
+	err = steps.run(StepResolve, "", func(r *StepResult) error {
+		info, _, err = inst.Resolve(releases, cmd.Release)
+		if err != nil {
+			return err
+		}
+		r.Message = fmt.Sprintf("Resolved %s to %s.", cmd.Release,
+			info.CleanTagName())
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get cached releases: %w", err)
+		return cmd.failed(p, steps, cmd.Release, info, err)
 	}
-	releases := release.Releases{}
+	releaseName := info.CleanTagName()
 
-	err = releases.Process(data, cmd.appOpts)
+	// The lock is only taken once the release is picked and resolved, so
+	// other nvimm processes are not kept waiting on the picker or the
+	// releases fetch.
+	unlock, err := lockPaths(cmd.appOpts)
 	if err != nil {
-		return fmt.Errorf("failed to process releases: %w", err)
+		return cmd.failed(p, steps, releaseName, info, err)
 	}
-
+	defer unlock()
 	mustSetCurrent := len(releases.Installed(cmd.appOpts.Path)) == 0
-	info, err := releases.Get(cmd.Release)
+	releasePath := inst.Path(releaseName)
+	existed := pathx.Exists(releasePath)
+	record, err := installRelease(cmd.appOpts.Context(), steps, inst, info)
 	if err != nil {
-		return err
+		if !existed {
+			os.RemoveAll(releasePath)
+		}
+		return cmd.failed(p, steps, releaseName, info, err)
 	}
-
-	goos := runtime.GOOS
-	goarch := runtime.GOARCH
-	assetUrl := ""
-	assetDigest := ""
-	assetFound := false
-
-	for _, asset := range info.Assets {
-		if asset.Name == getTarballName(info, goos, goarch) {
-			assetFound = true
-			assetUrl = fmt.Sprintf("%s/%s", strings.ReplaceAll(info.HtmlUrl, "tag", "download"), asset.Name)
-			assetDigest = asset.Digest
-			break
+	if info.Version().IsNightly() {
+		p.Statusf("Nightly build: %s\n", record.Describe())
+	}
+	if mustSetCurrent {
+		err = steps.run(StepActivate, "", func(r *StepResult) error {
+			if err := inst.Activate(releaseName); err != nil {
+				return err
+			}
+			r.Message = fmt.Sprintf("Version %s set as current.",
+				releaseName)
+			return nil
+		})
+		if err != nil {
+			return cmd.failed(p, steps, releaseName, info, err)
 		}
+	} else {
+		steps.skip(StepActivate, "Current release not changed.")
 	}
-
-	if !assetFound {
-		return fmt.Errorf("the os %s and arch %s cannot to be resolved as a valid nvim asset", goos, goarch)
+	p.Statusf("%s\n", steps.summary(releaseName))
+	if !p.Structured() {
+		return nil
 	}
+	return p.Render(InstallView{
+		Status:  StatusInstalled,
+		Release: newReleaseView(info, releasePath, true, mustSetCurrent),
+		Steps:   steps.results,
+	}, nil)
+}
 
-	spinner := NewSpinner("Downloading...")
-	spinner.Start()
-	downloadedRelease, err := downloadRelease(assetUrl, cachePath)
-	spinner.Stop("Download completed.")
-	if err != nil {
+// failed reports the install failure summary, rendering the steps run in
+// structured outputs, and returns err.
+func (cmd *InstallCommand) failed(p *Printer, steps *installSteps,
+	name string, info *release.Info, err error) error {
+	p.Statusf("%s\n", steps.summary(name))
+	if !p.Structured() {
 		return err
 	}
-	downloadedFile := filepath.Join(cachePath, downloadedRelease)
-	fmt.Printf("Downloaded file: %s\n", downloadedFile)
-
-	spinner = NewSpinner("Calculating SHA256 checksum...")
-	spinner.Start()
-	fingerprint, err := filehash.SHA256(downloadedFile)
-	spinner.Stop("Checksum calculated.")
-	if err != nil {
-		return err
+	view := InstallView{Status: StatusFailed, Steps: steps.results}
+	if info != nil {
+		view.Release = newReleaseView(info, "", false, false)
+	}
+	if rerr := p.Render(view, nil); rerr != nil {
+		return rerr
 	}
-	fmt.Printf("Calculated checksum: %s\n", fingerprint)
-	fmt.Printf("Expected checksum:   %s\n", assetDigest)
+	return err
+}
+
+// ExitInterrupted is the exit status used when a signal cancels the
+// command, following the shell convention for SIGINT.
+const ExitInterrupted = 130
+
+// ReleasesCacheName is the name the releases listing is cached under.
+const ReleasesCacheName = "nvimm_releases.json"
 
-	if fingerprint != assetDigest {
-		return fmt.Errorf("The downloaded file is corrupted: expected %s but got %s",
-			assetDigest, fingerprint)
+// LastGoodReleasesCacheName is the name the last valid releases listing
+// fetched is kept under, used when the listing cannot be fetched again.
+const LastGoodReleasesCacheName = "nvimm_releases.last_good.json"
+
+// loadReleases returns the processed releases, refreshing the cached listing
+// from GitHub when it is expired. A cached listing failing validation is
+// discarded and fetched again. When fetching fails, the expired listing or
+// the last known good one is used instead, with a warning.
+func loadReleases(appOpts *config.AppOptions) (release.Releases, error) {
+	return loadCachedReleases(appOpts, appOpts.Cacher(ReleasesCacheName),
+		appOpts.Cacher(LastGoodReleasesCacheName))
+}
+
+// loadCachedReleases is loadReleases with the caches of the listing and of
+// the last known good listing. Failing to write the caches is logged, the
+// fetched listing is used anyway.
+func loadCachedReleases(appOpts *config.AppOptions, releaseCacher,
+	lastGood cache.Cacher) (release.Releases, error) {
+	log := appOpts.Log()
+	data := cachedListing(appOpts, releaseCacher)
+
+	// TODO: use parametrized expiration time
+	if data == nil || releaseCacher.Expired(30*time.Minute) {
+		log.Info("refreshing the releases cache", "cache", releaseCacher)
+		fetched, err := newInstaller(appOpts).FetchListing(
+			appOpts.Context())
+		switch {
+		case err == nil:
+			if err := releaseCacher.Set(fetched); err != nil {
+				log.Warn("failed to cache the releases", "cache",
+					releaseCacher, "error", err)
+			}
+			if err := lastGood.Set(fetched); err != nil {
+				log.Warn("failed to keep the last known good releases",
+					"cache", lastGood, "error", err)
+			}
+			data = fetched
+		case errors.Is(err, context.Canceled):
+			return nil, err
+		case data != nil:
+			log.Warn("using the expired releases cache", "cache",
+				releaseCacher, "error", err)
+		default:
+			data = cachedListing(appOpts, lastGood)
+			if data == nil {
+				return nil, err
+			}
+			log.Warn("using the last known good releases", "cache",
+				lastGood, "error", err)
+		}
 	}
 
-	spinner = NewSpinner("Extracting archive...")
-	spinner.Start()
-	f, err := os.Open(downloadedFile)
+	releases := release.Releases{}
+	err := releases.Process(data, appOpts.MinRelease, appOpts.Log())
 	if err != nil {
-		spinner.Stop("Extraction failed.")
-		return err
+		return nil, fmt.Errorf("failed to process releases: %w", err)
 	}
-	defer f.Close()
+	return releases, nil
+}
 
-	gzr, err := gzip.NewReader(f)
+// cachedListing returns the releases listing held by the cacher, or nil if
+// it is empty or unreadable. A listing failing validation is removed so it
+// is fetched again.
+func cachedListing(appOpts *config.AppOptions, c cache.Cacher) []byte {
+	log := appOpts.Log()
+	data, err := c.Get()
+	if errors.Is(err, cache.ErrNotFound) {
+		return nil
+	}
 	if err != nil {
-		spinner.Stop("Extraction failed.")
-		return err
+		log.Warn("failed to read the releases cache", "cache", c, "error",
+			err)
+		return nil
 	}
-	defer gzr.Close()
-	archive.Untar(gzr, filepath.Dir(downloadedFile))
-	spinner.Stop("Extraction completed.")
-
-	releasePath := strings.ReplaceAll(
-		filepath.Join(cachePath, downloadedRelease), ".tar.gz", "")
-	spinner = NewSpinner("Copying files...")
-	spinner.Start()
-	dir.CopyAll(releasePath, filepath.Join(cmd.appOpts.Path, cmd.Release))
-	spinner.Stop("Installation completed.")
-	fmt.Printf("Installed at: %s\n", filepath.Join(cmd.appOpts.Path, cmd.Release))
-	if mustSetCurrent {
-		os.RemoveAll(filepath.Join(cmd.appOpts.Path, "current"))
-		os.Symlink(
-			filepath.Join(cmd.appOpts.Path, cmd.Release),
-			filepath.Join(cmd.appOpts.Path, "current"))
-		fmt.Printf("Version %s set as current.\n", cmd.Release)
+	if err := release.ValidateListing(data); err != nil {
+		log.Info("discarding the corrupt releases cache", "cache", c,
+			"error", err)
+		if err := c.Delete(); err != nil {
+			log.Warn("failed to remove the corrupt releases cache", "cache",
+				c, "error", err)
+		}
+		return nil
 	}
-
-	return nil
+	return data
 }
 
-func getTarballName(info *release.Info, goos string, goarch string) string {
+// newInstaller returns the installer for the paths, platform and logger in
+// the options.
+func newInstaller(appOpts *config.AppOptions) *installer.Installer {
+	inst := installer.New(appOpts.Path, appOpts.CachePath)
+	inst.MinRelease = appOpts.MinRelease
+	if appOpts.ApiUrl != "" {
+		inst.ReleasesUrl = protocol.ReleasesRepoUrl(appOpts.ApiUrl) +
+			"/releases"
+	}
+	inst.OS, inst.Arch = hostOS, hostArch
+	inst.Logger = appOpts.Log()
+	inst.Client = appOpts.HttpClient()
+	inst.Retry = installer.RetryPolicy(appOpts.RetryPolicy())
+	return inst
+}
 
-	if goos == "darwin" && goarch == "amd64" {
-		return "nvim-macos-x86_64.tar.gz"
+// lockPaths locks the install and cache directories, so no other nvimm
+// changes them until the returned function is called. When another nvimm
+// holds a lock, a status message tells so while waiting up to the lock
+// timeout. Directories that do not exist yet are not locked.
+func lockPaths(appOpts *config.AppOptions) (func(), error) {
+	locks := []*lock.Lock{}
+	unlock := func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Release()
+		}
 	}
-
-	if goos == "darwin" && goarch == "arm64" {
-		return "nvim-macos-arm64.tar.gz"
+	dirs := []string{filepath.Clean(appOpts.Path)}
+	if cachePath := filepath.Clean(appOpts.CachePath); cachePath != dirs[0] {
+		dirs = append(dirs, cachePath)
 	}
-
-	if goos == "linux" && goarch == "amd64" {
-		if info.VersionLess("0.10.4") {
-			return "nvim-linux64.tar.gz"
+	for _, dir := range dirs {
+		if !pathx.Exists(dir) {
+			continue
 		}
-		return "nvim-linux-x86_64.tar.gz"
-	}
-
-	if goos == "linux" && goarch == "arm64" {
-		return "nvim-linux-arm64.tar.gz"
+		l, err := lock.Acquire(appOpts.Context(), dir, 0)
+		if errors.Is(err, lock.ErrLocked) && appOpts.LockTimeout > 0 {
+			holder := "another nvimm"
+			if pid := lock.Holder(dir); pid > 0 {
+				holder = fmt.Sprintf("another nvimm (pid %d)", pid)
+			}
+			NewPrinter(appOpts).Statusf("Waiting for %s to finish...\n",
+				holder)
+			l, err = lock.Acquire(appOpts.Context(), dir,
+				appOpts.LockTimeout)
+		}
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		appOpts.Log().Debug("directory locked", "path", dir)
+		locks = append(locks, l)
 	}
-
-	return ""
+	return unlock, nil
 }
 
-func downloadRelease(url string, destDir string) (string, error) {
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return "", err
-	}
-	resp, err := http.Get(url)
+// installRelease runs the install steps from download to place for the
+// release, reporting each of them. The returned record is also persisted
+// into the release directory. Canceling the context stops the download and
+// the extraction.
+func installRelease(ctx context.Context, steps *installSteps,
+	inst *installer.Installer, info *release.Info) (*release.InstallRecord,
+	error) {
+	var asset *release.Asset
+	var file string
+	err := steps.run(StepDownload, "", func(r *StepResult) error {
+		var err error
+		asset, err = inst.Asset(info)
+		if err != nil {
+			return err
+		}
+		if cached, ok := inst.Cached(asset); ok {
+			file = cached
+			r.Message = fmt.Sprintf("Using cached %s.", file)
+			return nil
+		}
+		progress := steps.p.Terminal().NewProgress("Downloading...",
+			int64(asset.Size))
+		defer progress.Clear()
+		file, err = inst.Download(ctx, info, asset, progress)
+		if err != nil {
+			return fmt.Errorf("download of %s failed: %w", asset.Name, err)
+		}
+		r.Message = fmt.Sprintf("Downloaded %s.", file)
+		if fi, err := os.Stat(file); err == nil {
+			r.Message = fmt.Sprintf("Downloaded %s, %s.", file,
+				ui.HumanSize(fi.Size()))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = steps.run(StepVerify, "Calculating SHA256 checksum...",
+		func(r *StepResult) error {
+			var fingerprint string
+			var redownloaded bool
+			var err error
+			file, fingerprint, redownloaded, err = inst.VerifyOrRedownload(
+				ctx, info, asset, file)
+			if errors.Is(err, installer.ErrNoChecksum) {
+				r.Outcome = ui.OutcomeWarn
+				r.Message = fmt.Sprintf("No checksum published for %s, "+
+					"verification skipped.", asset.Name)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			r.Message = fmt.Sprintf("Checksum verified: %s.", fingerprint)
+			if redownloaded {
+				r.Outcome = ui.OutcomeWarn
+				r.Message = fmt.Sprintf("Checksum verified after a "+
+					"mismatch, %s downloaded again: %s.", asset.Name,
+					fingerprint)
+			}
+			return nil
+		})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	filename := path.Base(url)
-	outPath := path.Join(destDir, filename)
-	out, err := os.Create(outPath)
+	var extracted string
+	err = steps.run(StepExtract, "Extracting archive...",
+		func(r *StepResult) error {
+			extracted, err = inst.Extract(ctx, file)
+			if err != nil {
+				return err
+			}
+			r.Message = "Archive extracted."
+			return nil
+		})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer out.Close()
-	if _, err = io.Copy(out, resp.Body); err != nil {
-		return "", err
+
+	var record *release.InstallRecord
+	err = steps.run(StepPlace, "Copying files...", func(r *StepResult) error {
+		record, err = inst.Place(info, asset, extracted)
+		if err != nil {
+			return err
+		}
+		r.Message = fmt.Sprintf("Installed at %s.",
+			inst.Path(info.CleanTagName()))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return filename, nil
+	return record, nil
+}
+
+// hostOS and hostArch identify the platform whose assets are installed.
+var (
+	hostOS   = runtime.GOOS
+	hostArch = runtime.GOARCH
+)
+
+// platformAsset returns the release asset for the running platform, or nil
+// if the release has none.
+func platformAsset(info *release.Info) *release.Asset {
+	return installer.PlatformAsset(info, hostOS, hostArch)
 }
 
 func (cmd *InstallCommand) SetAppOptions(opts *config.AppOptions) {
@@ -322,7 +524,13 @@ func (cmd *InstallCommand) SetAppOptions(opts *config.AppOptions) {
 }
 
 type ListCommand struct {
-	appOpts *config.AppOptions
+	Installed  bool   `long:"installed" description:"Only list installed releases"`
+	Available  bool   `long:"available" description:"Only list releases available to install"`
+	Prerelease bool   `long:"prerelease" description:"Only list prereleases, like nightly"`
+	Since      string `long:"since" value-name:"version" description:"Only list releases since this version"`
+	Limit      int    `long:"limit" description:"Maximum number of releases listed"`
+	Long       bool   `short:"l" long:"long" description:"Show publish date, download size, platform asset and size on disk"`
+	appOpts    *config.AppOptions
 }
 
 func (cmd *ListCommand) Execute(args []string) error {
@@ -330,80 +538,159 @@ func (cmd *ListCommand) Execute(args []string) error {
 		return fmt.Errorf("cache path does not exist: %s",
 			cmd.appOpts.CachePath)
 	}
-	cachePath := cmd.appOpts.CachePath
-	releaseCacher := cache.NewFileCacher(cachePath, "nvimm_releases.json")
-	gt, err := protocol.NewGithubTransport()
+	if cmd.Limit < 0 {
+		return fmt.Errorf("the limit must be a positive number")
+	}
+	releases, err := loadReleases(cmd.appOpts)
 	if err != nil {
-		return fmt.Errorf("failed to create github transport: %w", err)
+		return err
 	}
-
-	// TODO: use parametrized expiration time
-	if releaseCacher.Expired(30 * time.Minute) {
-		res, err := gt.GetReleases()
-		if err != nil {
-			return fmt.Errorf("failed to get releases: %w", err)
-		}
-		data, err := io.ReadAll(res.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read response body: %w", err)
-		}
-		err = releaseCacher.Set(data)
+	if cmd.Since != "" {
+		releases, err = releases.Since(cmd.Since)
 		if err != nil {
-			return fmt.Errorf("failed to cache releases: %w", err)
+			return fmt.Errorf("invalid --since version: %w", err)
 		}
 	}
-
-	data, err := releaseCacher.Get()
-	if err != nil {
-		return fmt.Errorf("failed to get cached releases: %w", err)
+	if cmd.Prerelease {
+		prereleases := release.Releases{}
+		for _, info := range releases {
+			if releaseChannel(&info) == release.ChannelNightly ||
+				releaseChannel(&info) == ChannelPrerelease {
+				prereleases = append(prereleases, info)
+			}
+		}
+		releases = prereleases
 	}
-
-	releases := release.Releases{}
-	err = releases.Process(data, cmd.appOpts)
+	currentInstalled, err := currentRelease(cmd.appOpts.Path)
 	if err != nil {
-		return fmt.Errorf("failed to process releases: %w", err)
-	}
-
-	installed := releases.Installed(cmd.appOpts.Path)
-
-	fmt.Println("Installed versions")
-	if len(installed) == 0 {
-		fmt.Println("  no releases installed")
+		return err
 	}
+	view := cmd.filter(listView(&releases, cmd.appOpts.Path,
+		currentInstalled))
+	return NewPrinter(cmd.appOpts).Render(view, func(w io.Writer) error {
+		if cmd.Long {
+			return writeListLongTable(w, view)
+		}
+		return writeListTable(w, view, cmd.Installed || !cmd.Available)
+	})
+}
 
-	currentInstalled, err := os.Readlink(filepath.Join(cmd.appOpts.Path, "current"))
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("failed to read current symlink: %w", err)
+// filter keeps the sections requested by the command flags, limits the
+// number of releases and computes the size on disk for the long format.
+func (cmd *ListCommand) filter(view ListView) ListView {
+	filtered := ListView{Releases: []ReleaseView{}}
+	for _, release := range view.Releases {
+		if cmd.Installed != cmd.Available && release.Installed != cmd.Installed {
+			continue
 		}
+		if cmd.Limit > 0 && len(filtered.Releases) == cmd.Limit {
+			break
+		}
+		if cmd.Long && release.Installed {
+			release.InstallSize = dirSize(release.Path)
+		}
+		filtered.Releases = append(filtered.Releases, release)
 	}
+	return filtered
+}
 
+// listView creates the view of the installed releases, including the nightly
+// snapshot, followed by the available ones.
+func listView(releases *release.Releases, path string,
+	currentInstalled string) ListView {
+	view := ListView{Releases: []ReleaseView{}}
+	installed := releases.Installed(path)
 	for _, info := range installed {
-		ident := "  "
-		if filepath.Base(currentInstalled) == info.CleanTagName() {
-			ident = "* "
+		releasePath := filepath.Join(path, info.CleanTagName())
+		view.Releases = append(view.Releases, newReleaseView(&info,
+			releasePath, true, currentInstalled == info.CleanTagName()))
+		if info.Version().IsNightly() &&
+			pathx.Exists(filepath.Join(path, NightlySnapshot)) {
+			view.Releases = append(view.Releases, installedView(path,
+				NightlySnapshot, currentInstalled == NightlySnapshot))
 		}
-		if info.Stable == true {
-			fmt.Printf("%s%s (stable)\n", ident, info.CleanTagName())
+	}
+	for _, info := range releases.Available(installed) {
+		view.Releases = append(view.Releases, newReleaseView(&info, "",
+			false, false))
+	}
+	return view
+}
+
+// writeListTable writes the installed and available releases in the human
+// readable format. The installed section is omitted when showInstalled is
+// false, which happens when only available releases were requested.
+func writeListTable(w io.Writer, view ListView, showInstalled bool) error {
+	installed := 0
+	if showInstalled {
+		fmt.Fprintln(w, "Installed versions")
+	}
+	for _, release := range view.Releases {
+		if !release.Installed {
 			continue
 		}
-		fmt.Printf("%s%s\n", ident, info.CleanTagName())
+		installed++
+		fmt.Fprintf(w, "%s%s\n", release.marker(), release.label())
+	}
+	if showInstalled && installed == 0 {
+		fmt.Fprintln(w, "  no releases installed")
 	}
 
-	available := releases.Available(installed)
-	if len(available) > 0 {
-		fmt.Println("\nAvailable versions")
+	if installed < len(view.Releases) {
+		if showInstalled {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, "Available versions")
 	}
-	for _, info := range available {
-		if info.Stable == true {
-			fmt.Printf("  %s (stable)\n", info.CleanTagName())
+	for _, release := range view.Releases {
+		if release.Installed {
 			continue
 		}
-		fmt.Printf("  %s\n", info.CleanTagName())
+		fmt.Fprintf(w, "  %s\n", release.label())
 	}
 	return nil
 }
 
+// writeListLongTable writes the releases with their publish date, download
+// size, platform asset availability and size on disk.
+func writeListLongTable(w io.Writer, view ListView) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Version\tPublished\tDownload\tAsset\tOn disk")
+	for _, release := range view.Releases {
+		published := "-"
+		if !release.PublishedAt.IsZero() {
+			published = release.PublishedAt.Format(time.DateOnly)
+		}
+		download, asset := "-", "no"
+		if release.PlatformAsset {
+			download, asset = ui.HumanSize(release.AssetSize), "yes"
+		}
+		onDisk := "-"
+		if release.Installed {
+			onDisk = ui.HumanSize(release.InstallSize)
+		}
+		fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\t%s\n", release.marker(),
+			release.label(), published, download, asset, onDisk)
+	}
+	return tw.Flush()
+}
+
+// dirSize returns the size in bytes of the regular files under path,
+// ignoring the files that cannot be read.
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
 func (cmd *ListCommand) SetAppOptions(opts *config.AppOptions) {
 	cmd.appOpts = opts
 }
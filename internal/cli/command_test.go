@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/candango/nvimm/internal/cache"
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/internal/githubtest"
+	"github.com/candango/nvimm/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingCacher is a memory cache whose writes always fail.
+type failingCacher struct {
+	*cache.MemoryCacher
+}
+
+func (c failingCacher) Set(data []byte) error {
+	return errors.New("disk full")
+}
+
+func (c failingCacher) String() string {
+	return "failing cache"
+}
+
+func TestLoadReleases(t *testing.T) {
+	recorded, err := os.ReadFile(filepath.Join("testdata", "releases.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// setup returns options using a fake GitHub and logging to the returned
+	// buffer.
+	setup := func(t *testing.T) (*config.AppOptions, *githubtest.Server,
+		*bytes.Buffer) {
+		opts := fixtureSetup(t, nil, nil)
+		srv := githubtest.NewServer(t, recorded)
+		opts.ApiUrl = srv.URL
+		logs := &bytes.Buffer{}
+		opts.Logger = logging.New(logs, logging.FormatText, slog.LevelInfo)
+		return opts, srv, logs
+	}
+	cacheFile := func(opts *config.AppOptions, name string) string {
+		return filepath.Join(opts.CachePath, name)
+	}
+	hits := func(srv *githubtest.Server) int {
+		return srv.Hits("/repos/neovim/neovim/releases")
+	}
+
+	t.Run("should discard a corrupt cache and fetch again", func(t *testing.T) {
+		opts, srv, logs := setup(t)
+		assert.NoError(t, os.WriteFile(cacheFile(opts, ReleasesCacheName),
+			[]byte(`[{"tag_name": "v0.11.5", "ass`), 0644))
+		releases, err := loadReleases(opts)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, releases)
+		assert.Equal(t, 1, hits(srv))
+		assert.Contains(t, logs.String(), "discarding the corrupt releases cache")
+
+		data, err := os.ReadFile(cacheFile(opts, ReleasesCacheName))
+		assert.NoError(t, err)
+		lastGood, err := os.ReadFile(cacheFile(opts,
+			LastGoodReleasesCacheName))
+		assert.NoError(t, err)
+		assert.Equal(t, data, lastGood)
+
+		_, err = loadReleases(opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, hits(srv))
+	})
+
+	t.Run("should fall back to the last known good releases", func(t *testing.T) {
+		opts, srv, logs := setup(t)
+		_, err := loadReleases(opts)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(cacheFile(opts, ReleasesCacheName),
+			[]byte(`{"message": "Bad credentials"}`), 0644))
+		srv.SetRateLimited(true)
+
+		releases, err := loadReleases(opts)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, releases)
+		assert.Equal(t, 2, hits(srv))
+		assert.Contains(t, logs.String(), "using the last known good releases")
+		assert.Contains(t, logs.String(), "rate limit exceeded")
+		assert.NoFileExists(t, cacheFile(opts, ReleasesCacheName))
+	})
+
+	t.Run("should use the expired cache when fetching fails", func(t *testing.T) {
+		opts, srv, logs := setup(t)
+		_, err := loadReleases(opts)
+		assert.NoError(t, err)
+		expired := time.Now().Add(-time.Hour)
+		assert.NoError(t, os.Chtimes(cacheFile(opts, ReleasesCacheName),
+			expired, expired))
+		srv.SetReleases([]byte(`<html>502 Bad Gateway</html>`))
+
+		_, err = loadReleases(opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, hits(srv))
+		assert.Contains(t, logs.String(), "using the expired releases cache")
+	})
+
+	t.Run("should use the fetched releases when caching fails",
+		func(t *testing.T) {
+			opts, srv, logs := setup(t)
+			releaseCacher := failingCacher{cache.NewMemoryCacher()}
+			lastGood := failingCacher{cache.NewMemoryCacher()}
+			releases, err := loadCachedReleases(opts, releaseCacher, lastGood)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, releases)
+			assert.Equal(t, 1, hits(srv))
+			assert.Contains(t, logs.String(), "failed to cache the releases")
+			assert.Contains(t, logs.String(),
+				"failed to keep the last known good releases")
+			assert.Contains(t, logs.String(), "disk full")
+		})
+
+	t.Run("should never cache invalid listings", func(t *testing.T) {
+		opts, srv, _ := setup(t)
+		srv.SetReleases([]byte(`{"message": "Bad credentials"}`))
+		_, err := loadReleases(opts)
+		assert.ErrorContains(t, err, `error payload "Bad credentials"`)
+		assert.NoFileExists(t, cacheFile(opts, ReleasesCacheName))
+		assert.NoFileExists(t, cacheFile(opts, LastGoodReleasesCacheName))
+	})
+}
@@ -0,0 +1,474 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/candango/nvimm/internal/cache"
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/internal/protocol"
+	"github.com/candango/nvimm/release"
+)
+
+const (
+	CheckOK    = "ok"
+	CheckWarn  = "warn"
+	CheckFail  = "fail"
+	CheckFixed = "fixed"
+	CheckSkip  = "skip"
+)
+
+// ErrProblemsFound is returned by the doctor command when a check fails.
+var ErrProblemsFound = errors.New("nvimm doctor found problems")
+
+type DoctorCommand struct {
+	Fix     bool `long:"fix" description:"Apply safe repairs, like creating missing directories, removing broken installs and the corrupt releases cache"`
+	Offline bool `long:"offline" description:"Skip the GitHub reachability and rate limit check"`
+	appOpts *config.AppOptions
+}
+
+func (cmd *DoctorCommand) Execute(args []string) error {
+	d := &doctor{
+		opts:    cmd.appOpts,
+		fix:     cmd.Fix,
+		pathEnv: os.Getenv("PATH"),
+	}
+	if !cmd.Offline {
+		d.rateLimit = func() (*protocol.RateLimit, error) {
+			gt, err := protocol.NewGithubTransport(cmd.appOpts.ApiUrl,
+				cmd.appOpts.HttpClient(), cmd.appOpts.Log())
+			if err != nil {
+				return nil, err
+			}
+			return gt.GetRateLimit(cmd.appOpts.Context())
+		}
+	}
+	if cmd.Fix {
+		unlock, err := lockPaths(cmd.appOpts)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	view := d.run()
+	err := NewPrinter(cmd.appOpts).Render(view, func(w io.Writer) error {
+		return writeDoctorTable(w, view)
+	})
+	if err != nil {
+		return err
+	}
+	if view.Problems > 0 {
+		return fmt.Errorf("%w: %d checks failed", ErrProblemsFound,
+			view.Problems)
+	}
+	return nil
+}
+
+func (cmd *DoctorCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+// DiagnosesPaths keeps the missing nvimm directories from being created
+// before doctor checks them.
+func (cmd *DoctorCommand) DiagnosesPaths() {}
+
+// CheckResult is the outcome of one doctor check. Fix is an actionable hint
+// for problems that were not repaired, or only partly.
+type CheckResult struct {
+	Check   string `json:"check" yaml:"check"`
+	Status  string `json:"status" yaml:"status"`
+	Message string `json:"message" yaml:"message"`
+	Fix     string `json:"fix,omitempty" yaml:"fix,omitempty"`
+}
+
+// DoctorView is the result of the doctor command. Problems counts the failed
+// checks.
+type DoctorView struct {
+	Checks   []CheckResult `json:"checks" yaml:"checks"`
+	Problems int           `json:"problems" yaml:"problems"`
+}
+
+// doctor runs the checks against the options. PATH is checked against
+// pathEnv and GitHub through rateLimit, which is nil when offline.
+type doctor struct {
+	opts      *config.AppOptions
+	fix       bool
+	pathEnv   string
+	rateLimit func() (*protocol.RateLimit, error)
+}
+
+func (d *doctor) run() DoctorView {
+	view := DoctorView{Checks: []CheckResult{}}
+	checks := [][]CheckResult{
+		{d.checkDir("path dir", d.opts.Path)},
+		{d.checkDir("cache dir", d.opts.CachePath)},
+		{d.checkDir("config dir", d.opts.ConfigDir)},
+		{d.checkCurrent()},
+		d.checkPath(),
+		d.checkInstalls(),
+		{d.checkCache()},
+		{d.checkGithub()},
+	}
+	for _, results := range checks {
+		for _, result := range results {
+			if result.Status == CheckFail {
+				view.Problems++
+			}
+			view.Checks = append(view.Checks, result)
+		}
+	}
+	return view
+}
+
+// checkDir checks that dir is a writable directory, creating it when missing
+// if fixing.
+func (d *doctor) checkDir(check string, dir string) CheckResult {
+	result := CheckResult{Check: check}
+	fi, err := os.Stat(dir)
+	switch {
+	case os.IsNotExist(err) && d.fix:
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			result.Status = CheckFail
+			result.Message = fmt.Sprintf("failed to create %s: %v", dir, err)
+			return result
+		}
+		result.Status = CheckFixed
+		result.Message = fmt.Sprintf("created %s", dir)
+		return result
+	case os.IsNotExist(err):
+		result.Status = CheckFail
+		result.Message = fmt.Sprintf("%s does not exist", dir)
+		result.Fix = fmt.Sprintf("run 'nvimm doctor --fix' or 'mkdir -p %s'",
+			dir)
+		return result
+	case err != nil:
+		result.Status = CheckFail
+		result.Message = err.Error()
+		return result
+	case !fi.IsDir():
+		result.Status = CheckFail
+		result.Message = fmt.Sprintf("%s is not a directory", dir)
+		result.Fix = fmt.Sprintf("move %s away", dir)
+		return result
+	}
+	f, err := os.CreateTemp(dir, ".nvimm-doctor-*")
+	if err != nil {
+		result.Status = CheckFail
+		result.Message = fmt.Sprintf("%s is not writable", dir)
+		result.Fix = fmt.Sprintf("run 'chmod u+rwx %s' or fix its owner", dir)
+		return result
+	}
+	f.Close()
+	os.Remove(f.Name())
+	result.Status = CheckOK
+	result.Message = fmt.Sprintf("%s is writable", dir)
+	return result
+}
+
+// checkCurrent checks that the current symlink points to an installed
+// release. When fixing, a dangling symlink is removed, the release to use is
+// left for the user to pick.
+func (d *doctor) checkCurrent() CheckResult {
+	result := CheckResult{Check: "current"}
+	link := filepath.Join(d.opts.Path, "current")
+	fi, err := os.Lstat(link)
+	if os.IsNotExist(err) {
+		result.Status = CheckWarn
+		result.Message = "no release is set as current"
+		result.Fix = "run 'nvimm current --pick'"
+		return result
+	}
+	if err != nil {
+		result.Status = CheckFail
+		result.Message = err.Error()
+		return result
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		result.Status = CheckFail
+		result.Message = fmt.Sprintf("%s is not a symlink", link)
+		result.Fix = fmt.Sprintf("move %s away and run 'nvimm current "+
+			"<release>'", link)
+		return result
+	}
+	target, err := os.Readlink(link)
+	if err != nil {
+		result.Status = CheckFail
+		result.Message = err.Error()
+		return result
+	}
+	if _, err := os.Stat(target); err != nil {
+		if !d.fix {
+			result.Status = CheckFail
+			result.Message = fmt.Sprintf("current points to missing %s",
+				target)
+			result.Fix = "run 'nvimm current --pick' or 'nvimm doctor --fix'"
+			return result
+		}
+		if err := os.Remove(link); err != nil {
+			result.Status = CheckFail
+			result.Message = err.Error()
+			return result
+		}
+		result.Status = CheckFixed
+		result.Message = fmt.Sprintf("removed current pointing to missing %s",
+			target)
+		result.Fix = "run 'nvimm current --pick'"
+		return result
+	}
+	name := filepath.Base(target)
+	if !isExecutable(filepath.Join(target, "bin", nvimBinary())) {
+		result.Status = CheckFail
+		result.Message = fmt.Sprintf("current release %s has no nvim binary",
+			name)
+		result.Fix = fmt.Sprintf("reinstall it with 'nvimm install %s'", name)
+		return result
+	}
+	result.Status = CheckOK
+	result.Message = fmt.Sprintf("current points to %s", name)
+	return result
+}
+
+// checkPath checks that the bin directory of the current release comes
+// first in PATH and that no other nvim binary shadows it.
+func (d *doctor) checkPath() []CheckResult {
+	binDir := filepath.Join(d.opts.Path, "current", "bin")
+	entries := filepath.SplitList(d.pathEnv)
+	position := -1
+	for i, entry := range entries {
+		if filepath.Clean(entry) == binDir {
+			position = i
+			break
+		}
+	}
+	if position == -1 {
+		return []CheckResult{{
+			Check:   "PATH",
+			Status:  CheckFail,
+			Message: fmt.Sprintf("%s is not on PATH", binDir),
+			Fix: fmt.Sprintf("add 'export PATH=\"%s:$PATH\"' to your "+
+				"shell profile", binDir),
+		}}
+	}
+
+	path := CheckResult{
+		Check:   "PATH",
+		Status:  CheckOK,
+		Message: fmt.Sprintf("%s is first on PATH", binDir),
+	}
+	if position > 0 {
+		path.Status = CheckWarn
+		path.Message = fmt.Sprintf("%s is on PATH at position %d",
+			binDir, position+1)
+		path.Fix = fmt.Sprintf("move %s to the start of PATH", binDir)
+	}
+
+	shadowing := CheckResult{
+		Check:   "shadowing",
+		Status:  CheckOK,
+		Message: "no other nvim comes before nvimm on PATH",
+	}
+	others := []string{}
+	for _, entry := range entries[:position] {
+		bin := filepath.Join(entry, nvimBinary())
+		if entry != "" && isExecutable(bin) {
+			others = append(others, bin)
+		}
+	}
+	if len(others) > 0 {
+		shadowing.Status = CheckFail
+		shadowing.Message = fmt.Sprintf("%s shadows the nvim managed by "+
+			"nvimm", strings.Join(others, ", "))
+		shadowing.Fix = fmt.Sprintf("move %s to the start of PATH or "+
+			"uninstall the other nvim", binDir)
+	}
+	return []CheckResult{path, shadowing}
+}
+
+// checkInstalls reports installed release directories without an nvim
+// binary, left behind by interrupted installs. When fixing, they are removed
+// unless set as current. Directories that are not release installs, see
+// isReleaseDir, are left alone.
+func (d *doctor) checkInstalls() []CheckResult {
+	entries, err := os.ReadDir(d.opts.Path)
+	if err != nil {
+		return []CheckResult{{Check: "installs", Status: CheckFail,
+			Message: err.Error()}}
+	}
+	current, _ := currentRelease(d.opts.Path)
+	results := []CheckResult{}
+	count := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "current" {
+			continue
+		}
+		name := entry.Name()
+		dir := filepath.Join(d.opts.Path, name)
+		if !isReleaseDir(dir) {
+			continue
+		}
+		if isExecutable(filepath.Join(dir, "bin", nvimBinary())) {
+			count++
+			continue
+		}
+		result := CheckResult{Check: "installs"}
+		if d.fix && name != current {
+			if err := os.RemoveAll(dir); err != nil {
+				result.Status = CheckFail
+				result.Message = fmt.Sprintf("failed to remove broken "+
+					"install %s: %v", name, err)
+			} else {
+				result.Status = CheckFixed
+				result.Message = fmt.Sprintf("removed broken install %s", name)
+			}
+			results = append(results, result)
+			continue
+		}
+		result.Status = CheckFail
+		result.Message = fmt.Sprintf("install %s has no nvim binary", name)
+		result.Fix = fmt.Sprintf("run 'nvimm install %s' again or 'nvimm "+
+			"doctor --fix' to remove it", name)
+		if name == current {
+			result.Fix = fmt.Sprintf("run 'nvimm install %s' again", name)
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		results = append(results, CheckResult{Check: "installs",
+			Status: CheckOK, Message: fmt.Sprintf("%d releases installed",
+				count)})
+	}
+	return results
+}
+
+// isReleaseDir returns true if the directory is named after a release
+// version or channel, is the NightlySnapshot or holds an install record, the
+// only directories nvimm installs releases into.
+func isReleaseDir(dir string) bool {
+	name := filepath.Base(dir)
+	if _, err := release.ParseVersion(name); err == nil {
+		return true
+	}
+	if name == NightlySnapshot {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dir, release.InstallRecordFile))
+	return err == nil
+}
+
+// checkCache checks that the cached releases can be processed. When fixing,
+// a corrupt cache is removed so it is fetched again.
+func (d *doctor) checkCache() CheckResult {
+	result := CheckResult{Check: "cache"}
+	cacher := d.opts.Cacher(ReleasesCacheName)
+	data, err := cacher.Get()
+	if errors.Is(err, cache.ErrNotFound) {
+		result.Status = CheckOK
+		result.Message = "releases cache is empty"
+		return result
+	}
+	if err == nil {
+		err = release.ValidateListing(data)
+	}
+	if err == nil {
+		releases := release.Releases{}
+		err = releases.Process(data, d.opts.MinRelease, d.opts.Log())
+	}
+	if err == nil {
+		result.Status = CheckOK
+		result.Message = "releases cache is valid"
+		return result
+	}
+	if d.fix {
+		if rerr := cacher.Delete(); rerr != nil {
+			result.Status = CheckFail
+			result.Message = rerr.Error()
+			return result
+		}
+		result.Status = CheckFixed
+		result.Message = fmt.Sprintf("removed corrupt releases cache: %v",
+			err)
+		return result
+	}
+	result.Status = CheckFail
+	result.Message = fmt.Sprintf("releases cache is corrupt: %v", err)
+	result.Fix = fmt.Sprintf("run 'nvimm doctor --fix' or remove %s",
+		cacher)
+	return result
+}
+
+// checkGithub checks that the GitHub API is reachable and the rate limit is
+// not exhausted.
+func (d *doctor) checkGithub() CheckResult {
+	result := CheckResult{Check: "github"}
+	if d.rateLimit == nil {
+		result.Status = CheckSkip
+		result.Message = "skipped in offline mode"
+		return result
+	}
+	rl, err := d.rateLimit()
+	if err != nil {
+		result.Status = CheckFail
+		result.Message = fmt.Sprintf("GitHub is not reachable: %v", err)
+		result.Fix = "check your network connection and proxy settings"
+		return result
+	}
+	reset := rl.ResetAt().Local().Format(time.TimeOnly)
+	result.Message = fmt.Sprintf("GitHub is reachable, %d of %d requests "+
+		"left until %s", rl.Remaining, rl.Limit, reset)
+	switch {
+	case rl.Remaining == 0:
+		result.Status = CheckFail
+		result.Message = fmt.Sprintf("GitHub rate limit exhausted until %s",
+			reset)
+		result.Fix = fmt.Sprintf("wait until %s, cached releases are still "+
+			"used meanwhile", reset)
+	case rl.Remaining*10 < rl.Limit:
+		result.Status = CheckWarn
+	default:
+		result.Status = CheckOK
+	}
+	return result
+}
+
+// nvimBinary returns the file name of the nvim executable.
+func nvimBinary() string {
+	if hostOS == "windows" {
+		return "nvim.exe"
+	}
+	return "nvim"
+}
+
+// isExecutable returns true if path is a regular file that can be executed.
+func isExecutable(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	return hostOS == "windows" || fi.Mode()&0111 != 0
+}
+
+// writeDoctorTable writes the checks in the human readable format, with the
+// fix hint below each unrepaired problem.
+func writeDoctorTable(w io.Writer, view DoctorView) error {
+	for _, check := range view.Checks {
+		fmt.Fprintf(w, "%-7s %-10s %s\n", "["+check.Status+"]", check.Check,
+			check.Message)
+		if check.Fix != "" {
+			fmt.Fprintf(w, "%-18s fix: %s\n", "", check.Fix)
+		}
+	}
+	switch view.Problems {
+	case 0:
+		fmt.Fprintln(w, "\nNo problems found")
+	case 1:
+		fmt.Fprintln(w, "\n1 problem found")
+	default:
+		fmt.Fprintf(w, "\n%d problems found\n", view.Problems)
+	}
+	return nil
+}
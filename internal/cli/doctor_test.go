@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/internal/protocol"
+	"github.com/candango/nvimm/release"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureSetup creates the nvimm directories with the given releases
+// installed, each with an executable nvim binary unless listed as broken.
+func fixtureSetup(t *testing.T, installed []string,
+	broken []string) *config.AppOptions {
+	t.Helper()
+	hostOS = "linux"
+	t.Cleanup(func() {
+		hostOS = runtime.GOOS
+	})
+	root := t.TempDir()
+	opts := &config.AppOptions{
+		Path:       filepath.Join(root, "nvimm"),
+		CachePath:  filepath.Join(root, "cache"),
+		ConfigDir:  filepath.Join(root, "config"),
+		MinRelease: "0.7.0",
+	}
+	for _, dir := range []string{opts.Path, opts.CachePath, opts.ConfigDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range append(installed, broken...) {
+		if err := os.MkdirAll(filepath.Join(opts.Path, name, "bin"),
+			0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range installed {
+		writeExecutable(t, filepath.Join(opts.Path, name, "bin", "nvim"))
+	}
+	return opts
+}
+
+func writeExecutable(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkStatus(view DoctorView, check string) []string {
+	statuses := []string{}
+	for _, result := range view.Checks {
+		if result.Check == check {
+			statuses = append(statuses, result.Status)
+		}
+	}
+	return statuses
+}
+
+func TestDoctor(t *testing.T) {
+
+	t.Run("should pass on a healthy setup", func(t *testing.T) {
+		opts := fixtureSetup(t, []string{"0.11.5", "0.10.4"}, nil)
+		assert.NoError(t, setCurrent(opts.Path, "0.11.5"))
+		data, err := os.ReadFile(filepath.Join("testdata", "releases.json"))
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(opts.CachePath,
+			"nvimm_releases.json"), data, 0644))
+		d := &doctor{
+			opts: opts,
+			pathEnv: strings.Join([]string{
+				filepath.Join(opts.Path, "current", "bin"), "/usr/bin"},
+				string(os.PathListSeparator)),
+			rateLimit: func() (*protocol.RateLimit, error) {
+				return &protocol.RateLimit{Limit: 60, Remaining: 58,
+					Reset: time.Now().Unix()}, nil
+			},
+		}
+		view := d.run()
+		assert.Equal(t, 0, view.Problems)
+		for _, result := range view.Checks {
+			assert.Equal(t, CheckOK, result.Status, result.Message)
+		}
+	})
+
+	t.Run("should report problems with fixes", func(t *testing.T) {
+		opts := fixtureSetup(t, []string{"0.10.4"}, []string{"0.11.5"})
+		assert.NoError(t, setCurrent(opts.Path, "0.9.5"))
+		assert.NoError(t, os.WriteFile(filepath.Join(opts.CachePath,
+			"nvimm_releases.json"), []byte("{\"truncated"), 0644))
+		other := filepath.Join(t.TempDir(), "bin")
+		writeExecutable(t, filepath.Join(other, "nvim"))
+		d := &doctor{
+			opts: opts,
+			pathEnv: strings.Join([]string{other,
+				filepath.Join(opts.Path, "current", "bin")},
+				string(os.PathListSeparator)),
+			rateLimit: func() (*protocol.RateLimit, error) {
+				return nil, errors.New("no such host")
+			},
+		}
+		view := d.run()
+		assert.Equal(t, []string{CheckFail}, checkStatus(view, "current"))
+		assert.Equal(t, []string{CheckWarn}, checkStatus(view, "PATH"))
+		assert.Equal(t, []string{CheckFail}, checkStatus(view, "shadowing"))
+		assert.Equal(t, []string{CheckFail}, checkStatus(view, "installs"))
+		assert.Equal(t, []string{CheckFail}, checkStatus(view, "cache"))
+		assert.Equal(t, []string{CheckFail}, checkStatus(view, "github"))
+		assert.Equal(t, 5, view.Problems)
+		for _, result := range view.Checks {
+			if result.Status == CheckFail {
+				assert.NotEmpty(t, result.Fix, result.Message)
+			}
+		}
+	})
+
+	t.Run("should apply safe repairs", func(t *testing.T) {
+		opts := fixtureSetup(t, []string{"0.10.4", "0.11.5"},
+			[]string{"0.9.5"})
+		assert.NoError(t, setCurrent(opts.Path, "0.8.0"))
+		assert.NoError(t, os.WriteFile(filepath.Join(opts.CachePath,
+			"nvimm_releases.json"), []byte("[{]"), 0644))
+		assert.NoError(t, os.RemoveAll(opts.ConfigDir))
+		assert.NoError(t, os.MkdirAll(filepath.Join(opts.Path, "backups",
+			"init.lua.d"), 0755))
+		assert.NoError(t, os.MkdirAll(filepath.Join(opts.Path, "custom"),
+			0755))
+		assert.NoError(t, (&release.InstallRecord{TagName: "v0.10.0"}).Write(
+			filepath.Join(opts.Path, "custom")))
+		d := &doctor{
+			opts: opts,
+			fix:  true,
+			pathEnv: filepath.Join(opts.Path, "current", "bin") +
+				string(os.PathListSeparator) + "/usr/bin",
+		}
+		view := d.run()
+		assert.Equal(t, 0, view.Problems)
+		assert.Equal(t, []string{CheckFixed}, checkStatus(view, "config dir"))
+		assert.Equal(t, []string{CheckFixed}, checkStatus(view, "current"))
+		assert.Equal(t, []string{CheckFixed, CheckFixed},
+			checkStatus(view, "installs"))
+		assert.Equal(t, []string{CheckFixed}, checkStatus(view, "cache"))
+		assert.Equal(t, []string{CheckSkip}, checkStatus(view, "github"))
+
+		// The release to use is left for the user to pick.
+		current, err := currentRelease(opts.Path)
+		assert.NoError(t, err)
+		assert.Equal(t, "", current)
+		assert.NoFileExists(t, filepath.Join(opts.Path, "current"))
+		for _, result := range view.Checks {
+			if result.Check == "current" {
+				assert.Equal(t, "run 'nvimm current --pick'", result.Fix)
+			}
+		}
+		assert.NoDirExists(t, filepath.Join(opts.Path, "0.9.5"))
+		assert.NoDirExists(t, filepath.Join(opts.Path, "custom"))
+		assert.DirExists(t, filepath.Join(opts.Path, "backups", "init.lua.d"))
+		assert.DirExists(t, opts.ConfigDir)
+		assert.NoFileExists(t, filepath.Join(opts.CachePath,
+			"nvimm_releases.json"))
+	})
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/candango/iook/pathx"
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/internal/ui"
+	"github.com/candango/nvimm/release"
+)
+
+type InfoCommand struct {
+	NoNotes bool `long:"no-notes" description:"Do not show the release notes"`
+	appOpts *config.AppOptions
+}
+
+func (cmd *InfoCommand) Usage() string {
+	return "<release>"
+}
+
+func (cmd *InfoCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("positional argument release was not informed\n")
+	}
+	releases, err := loadReleases(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	info, _, err := newInstaller(cmd.appOpts).Resolve(releases, args[0])
+	if err != nil {
+		return err
+	}
+	current, err := currentRelease(cmd.appOpts.Path)
+	if err != nil {
+		return err
+	}
+	releasePath := filepath.Join(cmd.appOpts.Path, info.CleanTagName())
+	view := newInfoView(info, releasePath, pathx.Exists(releasePath),
+		current == info.CleanTagName())
+	if cmd.NoNotes {
+		view.Notes = ""
+	}
+	return NewPrinter(cmd.appOpts).Render(view, func(w io.Writer) error {
+		return writeInfoTable(w, view)
+	})
+}
+
+func (cmd *InfoCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+// AssetView is the schema used to render a release asset.
+type AssetView struct {
+	Name     string `json:"name" yaml:"name"`
+	Size     int64  `json:"size" yaml:"size"`
+	Digest   string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Selected bool   `json:"selected" yaml:"selected"`
+}
+
+// InfoView is the result of the info command.
+type InfoView struct {
+	Name    string      `json:"name" yaml:"name"`
+	Author  string      `json:"author,omitempty" yaml:"author,omitempty"`
+	Url     string      `json:"url,omitempty" yaml:"url,omitempty"`
+	Release ReleaseView `json:"release" yaml:"release"`
+	Assets  []AssetView `json:"assets" yaml:"assets"`
+	Notes   string      `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+// newInfoView creates the view of the release details, flagging the asset
+// nvimm would install on this machine as selected.
+func newInfoView(info *release.Info, path string, installed bool,
+	current bool) InfoView {
+	view := InfoView{
+		Name:    info.Name,
+		Author:  info.Author.Login,
+		Url:     info.HtmlUrl,
+		Release: newReleaseView(info, path, installed, current),
+		Assets:  []AssetView{},
+		Notes:   info.Body,
+	}
+	selected := platformAsset(info)
+	for i, asset := range info.Assets {
+		view.Assets = append(view.Assets, AssetView{
+			Name:     asset.Name,
+			Size:     int64(asset.Size),
+			Digest:   asset.Digest,
+			Selected: selected == &info.Assets[i],
+		})
+	}
+	return view
+}
+
+// writeInfoTable writes the release details in the human readable format.
+func writeInfoTable(w io.Writer, view InfoView) error {
+	rv := view.Release
+	fmt.Fprintf(w, "%s (%s)\n\n", view.Name, rv.Tag)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Channel:\t%s\n", rv.Channel)
+	published := rv.PublishedAt.Format(time.DateOnly)
+	if view.Author != "" {
+		published += " by " + view.Author
+	}
+	fmt.Fprintf(tw, "Published:\t%s\n", published)
+	if rv.Build != "" {
+		fmt.Fprintf(tw, "Build:\t%s\n", rv.Build)
+	}
+	switch {
+	case rv.Current:
+		fmt.Fprintf(tw, "Installed:\tyes, current (%s)\n", rv.Path)
+	case rv.Installed:
+		fmt.Fprintf(tw, "Installed:\tyes (%s)\n", rv.Path)
+	default:
+		fmt.Fprintf(tw, "Installed:\tno\n")
+	}
+	if rv.PlatformAsset {
+		fmt.Fprintf(tw, "Asset:\t%s (%s)\n", rv.Asset,
+			ui.HumanSize(rv.AssetSize))
+	} else {
+		fmt.Fprintf(tw, "Asset:\tnone for %s/%s\n", hostOS, hostArch)
+	}
+	if view.Url != "" {
+		fmt.Fprintf(tw, "Url:\t%s\n", view.Url)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(view.Assets) > 0 {
+		fmt.Fprintln(w, "\nAssets")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, asset := range view.Assets {
+			marker := "  "
+			if asset.Selected {
+				marker = "* "
+			}
+			fmt.Fprintf(tw, "%s%s\t%s\t%s\n", marker, asset.Name,
+				ui.HumanSize(asset.Size), orDash(asset.Digest))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if view.Notes != "" {
+		fmt.Fprintln(w, "\nRelease notes")
+		fmt.Fprintln(w)
+		return renderMarkdown(w, view.Notes, "  ")
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	mdHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdBulletRe  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	mdOrderedRe = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	mdLinkRe    = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+	mdBoldRe    = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	mdCodeRe    = regexp.MustCompile("`([^`]+)`")
+	mdTagRe     = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdCommentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// renderMarkdown writes the markdown text in a plain terminal friendly form,
+// indented by indent. Headings are underlined, lists use bullets, code blocks
+// are indented and inline markup, links and html tags are simplified.
+func renderMarkdown(w io.Writer, text string, indent string) error {
+	text = mdCommentRe.ReplaceAllString(strings.ReplaceAll(text, "\r\n", "\n"),
+		"")
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	inCode := false
+	blank := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			fmt.Fprintf(w, "%s    %s\n", indent, line)
+			blank = false
+			continue
+		}
+		line = strings.TrimRight(mdTagRe.ReplaceAllString(line, ""), " \t")
+		if strings.TrimSpace(line) == "" {
+			if !blank {
+				fmt.Fprintln(w)
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		if m := mdHeadingRe.FindStringSubmatch(line); m != nil {
+			heading := renderInline(m[2])
+			underline := "-"
+			if len(m[1]) == 1 {
+				underline = "="
+			}
+			fmt.Fprintf(w, "%s%s\n%s%s\n", indent, heading, indent,
+				strings.Repeat(underline, len([]rune(heading))))
+			continue
+		}
+		if m := mdBulletRe.FindStringSubmatch(line); m != nil {
+			fmt.Fprintf(w, "%s%s• %s\n", indent, m[1], renderInline(m[2]))
+			continue
+		}
+		if m := mdOrderedRe.FindStringSubmatch(line); m != nil {
+			fmt.Fprintf(w, "%s%s%s. %s\n", indent, m[1], m[2],
+				renderInline(m[3]))
+			continue
+		}
+		fmt.Fprintf(w, "%s%s\n", indent, renderInline(line))
+	}
+	return scanner.Err()
+}
+
+// renderInline simplifies the inline markup of a markdown line.
+func renderInline(s string) string {
+	s = mdLinkRe.ReplaceAllStringFunc(s, func(link string) string {
+		m := mdLinkRe.FindStringSubmatch(link)
+		if m[1] == m[2] {
+			return m[1]
+		}
+		return fmt.Sprintf("%s (%s)", m[1], m[2])
+	})
+	s = mdBoldRe.ReplaceAllString(s, "$2")
+	return mdCodeRe.ReplaceAllString(s, "'$1'")
+}
+
+const (
+	SectionBreaking    = "breaking"
+	SectionDeprecation = "deprecation"
+)
+
+var (
+	mdBreakingRe    = regexp.MustCompile(`(?i)break`)
+	mdDeprecationRe = regexp.MustCompile(`(?i)deprecat`)
+)
+
+// NoteSection is a part of release notes started by a heading. The text
+// before the first heading is a section without title.
+type NoteSection struct {
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	Level int    `json:"level,omitempty" yaml:"level,omitempty"`
+	// Kind is SectionBreaking or SectionDeprecation for sections describing
+	// breaking changes or deprecations, including their subsections.
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Text string `json:"text" yaml:"text"`
+}
+
+// splitSections splits markdown text at its headings. Headings inside code
+// blocks are ignored.
+func splitSections(text string) []NoteSection {
+	text = mdCommentRe.ReplaceAllString(strings.ReplaceAll(text, "\r\n", "\n"),
+		"")
+	sections := []NoteSection{}
+	current := NoteSection{}
+	lines := []string{}
+	flush := func() {
+		current.Text = strings.TrimSpace(strings.Join(lines, "\n"))
+		if current.Title != "" || current.Text != "" {
+			sections = append(sections, current)
+		}
+		lines = []string{}
+	}
+	// parentLevel and parentKind track the heading whose kind is inherited
+	// by its subsections.
+	parentLevel, parentKind := 0, ""
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		m := mdHeadingRe.FindStringSubmatch(line)
+		if inCode || m == nil {
+			lines = append(lines, line)
+			continue
+		}
+		flush()
+		level := len(m[1])
+		if level <= parentLevel {
+			parentLevel, parentKind = 0, ""
+		}
+		kind := parentKind
+		switch {
+		case mdBreakingRe.MatchString(m[2]):
+			kind = SectionBreaking
+		case mdDeprecationRe.MatchString(m[2]):
+			kind = SectionDeprecation
+		}
+		if parentLevel == 0 && kind != "" {
+			parentLevel, parentKind = level, kind
+		}
+		current = NoteSection{Title: m[2], Level: level, Kind: kind}
+	}
+	flush()
+	return sections
+}
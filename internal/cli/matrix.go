@@ -0,0 +1,362 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/candango/nvimm/internal/config"
+)
+
+const (
+	MatrixPass  = "pass"
+	MatrixFail  = "fail"
+	MatrixError = "error"
+)
+
+// ErrMatrixFailed is returned by the matrix command when the command fails,
+// or can't be run, for a version.
+var ErrMatrixFailed = errors.New("nvimm matrix failed")
+
+type MatrixCommand struct {
+	Versions []string `short:"V" long:"versions" required:"true" description:"Comma separated releases to run the command with, can be repeated"`
+	Jobs     int      `short:"j" long:"jobs" default:"1" description:"Number of versions to run the command with at once"`
+	JUnit    string   `long:"junit" description:"Write a JUnit XML report to this file"`
+	appOpts  *config.AppOptions
+}
+
+func (cmd *MatrixCommand) Usage() string {
+	return "--versions <releases> -- <command> [arguments]"
+}
+
+func (cmd *MatrixCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("positional argument command was not informed\n")
+	}
+	if cmd.Jobs < 1 {
+		return fmt.Errorf("invalid number of jobs %d", cmd.Jobs)
+	}
+	m := &matrix{
+		opts:     cmd.appOpts,
+		versions: splitVersions(cmd.Versions),
+		command:  args,
+		jobs:     cmd.Jobs,
+		resolve: func(query string) (string, string, error) {
+			return installedNvim(cmd.appOpts, query, true)
+		},
+	}
+	view := m.run()
+	if cmd.JUnit != "" {
+		if err := writeJUnitFile(cmd.JUnit, view); err != nil {
+			return err
+		}
+	}
+	p := NewPrinter(cmd.appOpts)
+	for _, result := range view.Results {
+		if result.Status != MatrixPass && result.Output != "" {
+			p.Statusf("--- %s (%s) output:\n%s", result.Version,
+				orDash(result.Release), result.Output)
+			if !strings.HasSuffix(result.Output, "\n") {
+				p.Statusf("\n")
+			}
+		}
+	}
+	err := p.Render(view, func(w io.Writer) error {
+		return writeMatrixTable(w, view)
+	})
+	if err != nil {
+		return err
+	}
+	if view.Failed > 0 {
+		return fmt.Errorf("%w: %d of %d versions", ErrMatrixFailed,
+			view.Failed, len(view.Results))
+	}
+	return nil
+}
+
+func (cmd *MatrixCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+// MatrixResult is the outcome of the command run with one version. Version
+// is the release as informed by the user and Release the install it resolved
+// to.
+type MatrixResult struct {
+	Version  string  `json:"version" yaml:"version"`
+	Release  string  `json:"release,omitempty" yaml:"release,omitempty"`
+	Status   string  `json:"status" yaml:"status"`
+	ExitCode int     `json:"exit_code" yaml:"exit_code"`
+	Time     float64 `json:"time" yaml:"time"`
+	Message  string  `json:"message,omitempty" yaml:"message,omitempty"`
+	Output   string  `json:"output" yaml:"output"`
+}
+
+// MatrixView is the result of the matrix command.
+type MatrixView struct {
+	Command []string       `json:"command" yaml:"command"`
+	Results []MatrixResult `json:"results" yaml:"results"`
+	Passed  int            `json:"passed" yaml:"passed"`
+	Failed  int            `json:"failed" yaml:"failed"`
+}
+
+// matrix runs a command once for each version. Versions are resolved, and
+// installed when missing, one at a time before any command runs, so
+// downloads don't compete with each other or with the commands.
+type matrix struct {
+	opts     *config.AppOptions
+	versions []string
+	command  []string
+	jobs     int
+	// resolve returns the install name and nvim binary of a version.
+	resolve func(query string) (string, string, error)
+}
+
+func (m *matrix) run() MatrixView {
+	view := MatrixView{Command: m.command,
+		Results: make([]MatrixResult, len(m.versions))}
+	bins := make([]string, len(m.versions))
+	for i, version := range m.versions {
+		view.Results[i].Version = version
+		name, bin, err := m.resolve(version)
+		if err != nil {
+			view.Results[i].Status = MatrixError
+			view.Results[i].Message = err.Error()
+			continue
+		}
+		view.Results[i].Release = name
+		bins[i] = bin
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, m.jobs)
+	for i := range view.Results {
+		if bins[i] == "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(result *MatrixResult, bin string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			m.runVersion(result, bin)
+		}(&view.Results[i], bins[i])
+	}
+	wg.Wait()
+
+	for _, result := range view.Results {
+		if result.Status == MatrixPass {
+			view.Passed++
+		} else {
+			view.Failed++
+		}
+	}
+	return view
+}
+
+// runVersion runs the command with the directory of bin first on PATH and
+// NVIMM_RELEASE set to the release name, recording its combined output.
+func (m *matrix) runVersion(result *MatrixResult, bin string) {
+	log := m.opts.Log().With("version", result.Version, "release",
+		result.Release)
+	out := &bytes.Buffer{}
+	c := exec.Command(m.command[0], m.command[1:]...)
+	c.Env = append(envWithPath(os.Environ(), filepath.Dir(bin)),
+		"NVIMM_RELEASE="+result.Release)
+	c.Stdout, c.Stderr = out, out
+	// The command is looked up on the new PATH, so "nvim" finds bin.
+	if path, err := lookPathIn(m.command[0], c.Env); err == nil {
+		c.Path = path
+		c.Err = nil
+	}
+	log.Info("running command", "command", strings.Join(m.command, " "))
+	start := time.Now()
+	code, err := runForwardingSignals(c)
+	result.Time = time.Since(start).Round(time.Millisecond).Seconds()
+	result.Output = out.String()
+	result.ExitCode = code
+	switch {
+	case err != nil:
+		result.Status = MatrixError
+		result.Message = err.Error()
+	case code != 0:
+		result.Status = MatrixFail
+		result.Message = fmt.Sprintf("exit status %d", code)
+	default:
+		result.Status = MatrixPass
+	}
+	log.Info("command finished", "status", result.Status, "exit_code", code)
+}
+
+// splitVersions splits the comma separated versions, dropping empty and
+// repeated entries.
+func splitVersions(values []string) []string {
+	versions := []string{}
+	seen := map[string]bool{}
+	for _, value := range values {
+		for _, version := range strings.Split(value, ",") {
+			version = strings.TrimSpace(version)
+			if version == "" || seen[version] {
+				continue
+			}
+			seen[version] = true
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// envWithPath returns a copy of env with dir prepended to PATH.
+func envWithPath(env []string, dir string) []string {
+	result := make([]string, 0, len(env)+1)
+	found := false
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		if strings.EqualFold(key, "PATH") && !found {
+			found = true
+			kv = key + "=" + dir
+			if value != "" {
+				kv += string(os.PathListSeparator) + value
+			}
+		}
+		result = append(result, kv)
+	}
+	if !found {
+		result = append(result, "PATH="+dir)
+	}
+	return result
+}
+
+// lookPathIn looks file up in the PATH of env, like exec.LookPath does with
+// the PATH of nvimm.
+func lookPathIn(file string, env []string) (string, error) {
+	if strings.ContainsAny(file, `/\`) {
+		return exec.LookPath(file)
+	}
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.EqualFold(key, "PATH") {
+			continue
+		}
+		for _, dir := range filepath.SplitList(value) {
+			if dir == "" {
+				continue
+			}
+			if path, err := exec.LookPath(filepath.Join(dir, file)); err == nil {
+				return path, nil
+			}
+		}
+		break
+	}
+	return "", exec.ErrNotFound
+}
+
+// writeMatrixTable writes the results in the human readable format.
+func writeMatrixTable(w io.Writer, view MatrixView) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Version\tRelease\tResult\tTime\tMessage")
+	for _, result := range view.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.Version,
+			orDash(result.Release), result.Status,
+			secondsString(result.Time),
+			result.Message)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d failed\n", view.Passed,
+		view.Failed)
+	return err
+}
+
+// junitTestSuites is the root of a JUnit XML report. Each version is a test
+// case of a single suite.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the results as a JUnit XML report.
+func writeJUnit(w io.Writer, view MatrixView) error {
+	suite := junitTestSuite{Name: "nvimm matrix: " +
+		strings.Join(view.Command, " ")}
+	total := 0.0
+	for _, result := range view.Results {
+		tc := junitTestCase{
+			Name:      result.Version,
+			ClassName: "nvimm.matrix",
+			Time:      fmt.Sprintf("%.3f", result.Time),
+		}
+		if result.Release != "" && result.Release != result.Version {
+			tc.Name += " (" + result.Release + ")"
+		}
+		problem := &junitProblem{Message: result.Message, Text: result.Output}
+		switch result.Status {
+		case MatrixFail:
+			tc.Failure = problem
+			suite.Failures++
+		case MatrixError:
+			tc.Error = problem
+			suite.Errors++
+		default:
+			tc.SystemOut = result.Output
+		}
+		total += result.Time
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeJUnitFile(path string, view MatrixView) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create the JUnit report: %w", err)
+	}
+	if err := writeJUnit(f, view); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write the JUnit report: %w", err)
+	}
+	return f.Close()
+}
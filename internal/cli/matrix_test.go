@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatrix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake nvim binaries are shell scripts")
+	}
+	opts := fixtureSetup(t, nil, nil)
+	for _, name := range []string{"0.10.4", "0.11.5", "nightly"} {
+		script := fmt.Sprintf("#!/bin/sh\necho \"%s $NVIMM_RELEASE\"\n", name)
+		if name == "0.10.4" {
+			script += "exit 2\n"
+		}
+		bin := filepath.Join(opts.Path, name, "bin", "nvim")
+		assert.NoError(t, os.MkdirAll(filepath.Dir(bin), 0755))
+		assert.NoError(t, os.WriteFile(bin, []byte(script), 0755))
+	}
+	resolve := func(query string) (string, string, error) {
+		names := map[string]string{"0.10": "0.10.4", "stable": "0.11.5",
+			"nightly": "nightly"}
+		name, ok := names[query]
+		if !ok {
+			return "", "", errors.New("release 0.8 does not exists")
+		}
+		return name, filepath.Join(opts.Path, name, "bin", "nvim"), nil
+	}
+
+	t.Run("should run the command with each version", func(t *testing.T) {
+		for _, jobs := range []int{1, 3} {
+			m := &matrix{
+				opts:     opts,
+				versions: splitVersions([]string{"0.10,stable", " nightly,0.8,0.10"}),
+				command:  []string{"sh", "-c", "nvim"},
+				jobs:     jobs,
+				resolve:  resolve,
+			}
+			view := m.run()
+			assert.Equal(t, 2, view.Passed)
+			assert.Equal(t, 2, view.Failed)
+			assert.Len(t, view.Results, 4)
+
+			assert.Equal(t, "0.10", view.Results[0].Version)
+			assert.Equal(t, MatrixFail, view.Results[0].Status)
+			assert.Equal(t, 2, view.Results[0].ExitCode)
+			assert.Equal(t, "0.10.4 0.10.4\n", view.Results[0].Output)
+			assert.Equal(t, MatrixPass, view.Results[1].Status)
+			assert.Equal(t, "0.11.5 0.11.5\n", view.Results[1].Output)
+			assert.Equal(t, MatrixPass, view.Results[2].Status)
+			assert.Equal(t, "nightly", view.Results[2].Release)
+			assert.Equal(t, MatrixError, view.Results[3].Status)
+			assert.Contains(t, view.Results[3].Message, "does not exists")
+		}
+	})
+
+	t.Run("should write a JUnit report", func(t *testing.T) {
+		m := &matrix{
+			opts:     opts,
+			versions: []string{"0.10", "stable", "0.8"},
+			command:  []string{"nvim", "--headless"},
+			jobs:     2,
+			resolve:  resolve,
+		}
+		out := &bytes.Buffer{}
+		assert.NoError(t, writeJUnit(out, m.run()))
+		assert.True(t, strings.HasPrefix(out.String(), xml.Header))
+
+		report := junitTestSuites{}
+		assert.NoError(t, xml.Unmarshal(out.Bytes(), &report))
+		assert.Len(t, report.Suites, 1)
+		suite := report.Suites[0]
+		assert.Equal(t, "nvimm matrix: nvim --headless", suite.Name)
+		assert.Equal(t, 3, suite.Tests)
+		assert.Equal(t, 1, suite.Failures)
+		assert.Equal(t, 1, suite.Errors)
+		assert.Equal(t, "0.10 (0.10.4)", suite.Cases[0].Name)
+		assert.Equal(t, "exit status 2", suite.Cases[0].Failure.Message)
+		assert.Equal(t, "0.11.5 0.11.5\n", suite.Cases[1].SystemOut)
+		assert.Nil(t, suite.Cases[1].Failure)
+		assert.NotNil(t, suite.Cases[2].Error)
+	})
+
+	t.Run("should prepend the version to PATH", func(t *testing.T) {
+		sep := string(os.PathListSeparator)
+		env := envWithPath([]string{"HOME=/home/user",
+			"PATH=/usr/bin" + sep + "/bin"}, "/nvimm/0.11.5/bin")
+		assert.Equal(t, []string{"HOME=/home/user",
+			"PATH=/nvimm/0.11.5/bin" + sep + "/usr/bin" + sep + "/bin"}, env)
+		assert.Equal(t, []string{"PATH=/nvimm/0.11.5/bin"},
+			envWithPath(nil, "/nvimm/0.11.5/bin"))
+	})
+}
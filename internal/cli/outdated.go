@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/candango/nvimm/installer"
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/internal/ui"
+	"github.com/candango/nvimm/release"
+	"github.com/jessevdk/go-flags"
+)
+
+// updateCheckInterval is the minimum time between two update notices.
+const updateCheckInterval = 24 * time.Hour
+
+type OutdatedCommand struct {
+	appOpts *config.AppOptions
+}
+
+func (cmd *OutdatedCommand) Execute(args []string) error {
+	releases, err := loadReleases(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	installed := releases.Installed(cmd.appOpts.Path)
+	if len(installed) == 0 {
+		return fmt.Errorf("no releases installed yet")
+	}
+	current, err := currentRelease(cmd.appOpts.Path)
+	if err != nil {
+		return err
+	}
+	inst := newInstaller(cmd.appOpts)
+	stable, _, err := inst.Resolve(releases, release.ChannelStable)
+	if err != nil {
+		return err
+	}
+
+	view := OutdatedView{Releases: []OutdatedEntry{}}
+	for _, info := range installed {
+		entry := outdatedEntry(inst, releases, &info, stable)
+		entry.Current = current == info.CleanTagName()
+		view.Releases = append(view.Releases, entry)
+	}
+	return NewPrinter(cmd.appOpts).Render(view, func(w io.Writer) error {
+		return writeOutdatedTable(w, view)
+	})
+}
+
+// outdatedEntry compares the installed release against the newest patch of
+// its minor line and the stable release, resolved by the installer. For
+// nightly it tells if a newer build exists.
+func outdatedEntry(inst *installer.Installer, releases release.Releases,
+	info *release.Info, stable *release.Info) OutdatedEntry {
+	entry := OutdatedEntry{Version: info.CleanTagName()}
+	v := info.Version()
+	if v.IsNightly() {
+		record, err := release.ReadInstallRecord(
+			inst.Path(info.CleanTagName()))
+		if err != nil || record.OutdatedBy(info) {
+			entry.Build = info.DescribeBuild()
+			entry.Outdated = true
+		}
+		return entry
+	}
+
+	newest, _, err := inst.Resolve(releases, fmt.Sprintf("%d.%d", v.Major,
+		v.Minor))
+	if err == nil && v.Less(newest.Version()) {
+		entry.NewestPatch = newest.CleanTagName()
+		entry.Outdated = true
+	}
+	if v.Less(stable.Version()) {
+		entry.Stable = stable.CleanTagName()
+		entry.Outdated = true
+	}
+	return entry
+}
+
+// writeOutdatedTable writes the outdated entries in the human readable
+// format, using "-" when the installed release is already the newest.
+func writeOutdatedTable(w io.Writer, view OutdatedView) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Installed\tNewest patch\tStable")
+	for _, entry := range view.Releases {
+		ident := "  "
+		if entry.Current {
+			ident = "* "
+		}
+		patch := orDash(entry.NewestPatch)
+		if entry.Build != "" {
+			patch = "build " + entry.Build
+		}
+		fmt.Fprintf(tw, "%s%s\t%s\t%s\n", ident, entry.Version, patch,
+			orDash(entry.Stable))
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (cmd *OutdatedCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+// WithUpdateNotice wraps a command handler printing a one-line notice after
+// successful commands when a stable release newer than the current one
+// exists. The check is opt-in through AppOptions.CheckUpdates and runs at
+// most once per updateCheckInterval.
+func WithUpdateNotice(opts *config.AppOptions,
+	handler func(cmd flags.Commander, args []string) error,
+) func(cmd flags.Commander, args []string) error {
+	return func(cmd flags.Commander, args []string) error {
+		err := handler(cmd, args)
+		if err != nil || !opts.CheckUpdates || opts.Quiet {
+			return err
+		}
+		if _, ok := cmd.(*OutdatedCommand); ok {
+			return nil
+		}
+		if notice := updateNotice(opts); notice != "" {
+			u := ui.New(os.Stderr, opts.NoColor)
+			u.Printf("%s\n", u.Yellow(notice))
+		}
+		return nil
+	}
+}
+
+// updateNotice returns the notice to be printed, or an empty string if the
+// check is throttled, fails or finds nothing newer.
+func updateNotice(opts *config.AppOptions) string {
+	log := opts.Log()
+	checkCacher := opts.Cacher("nvimm_update_check")
+	if !checkCacher.Expired(updateCheckInterval) {
+		log.Debug("update check throttled")
+		return ""
+	}
+	err := checkCacher.Set([]byte(time.Now().Format(time.RFC3339)))
+	if err != nil {
+		log.Warn("failed to record the update check", "error", err)
+		return ""
+	}
+
+	current, err := currentRelease(opts.Path)
+	if err != nil || current == "" {
+		return ""
+	}
+	currentVersion, err := release.ParseVersion(current)
+	if err != nil || currentVersion.IsNightly() {
+		return ""
+	}
+	releases, err := loadReleases(opts)
+	if err != nil {
+		log.Warn("update check failed", "error", err)
+		return ""
+	}
+	stable, _, err := newInstaller(opts).Resolve(releases,
+		release.ChannelStable)
+	if err != nil || !currentVersion.Less(stable.Version()) {
+		return ""
+	}
+	return fmt.Sprintf("A newer stable Neovim is available: %s (current "+
+		"%s), run 'nvimm upgrade --to-stable' to install it.",
+		stable.CleanTagName(), current)
+}
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/candango/nvimm/installer"
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/internal/ui"
+	"github.com/candango/nvimm/release"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+const (
+	ChannelRelease    = "release"
+	ChannelPrerelease = "prerelease"
+)
+
+// Printer renders command results in the output format selected by the user.
+// Results are written to Out while progress messages are written to Status,
+// which is the standard error in structured formats so the standard output
+// can be parsed.
+type Printer struct {
+	Format string
+	Out    io.Writer
+	Status io.Writer
+	// UI renders spinners and progress to Status, see Terminal.
+	UI *ui.UI
+}
+
+// NewPrinter creates a Printer for the output format in the options. Status
+// messages are discarded in quiet mode.
+func NewPrinter(opts *config.AppOptions) *Printer {
+	p := &Printer{Format: opts.Output, Out: os.Stdout, Status: os.Stdout}
+	if p.Format == "" {
+		p.Format = OutputTable
+	}
+	if p.Structured() {
+		p.Status = os.Stderr
+	}
+	if opts.Quiet {
+		p.Status = io.Discard
+	}
+	p.UI = ui.New(p.Status, opts.NoColor)
+	return p
+}
+
+// Terminal returns the UI writing to Status. If the UI was not set, a UI
+// writing plain lines is used.
+func (p *Printer) Terminal() *ui.UI {
+	if p.UI == nil {
+		p.UI = &ui.UI{Out: p.Status}
+	}
+	return p.UI
+}
+
+// Structured returns true if the results are rendered as JSON or YAML.
+func (p *Printer) Structured() bool {
+	return p.Format == OutputJSON || p.Format == OutputYAML
+}
+
+// Render writes v in the selected structured format, or calls table to write
+// it in the human readable format.
+func (p *Printer) Render(v any, table func(w io.Writer) error) error {
+	switch p.Format {
+	case OutputJSON:
+		enc := json.NewEncoder(p.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case OutputYAML:
+		enc := yaml.NewEncoder(p.Out)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case OutputTable:
+		return table(p.Out)
+	}
+	return fmt.Errorf("unknown output format %s", p.Format)
+}
+
+// Statusf prints a progress message.
+func (p *Printer) Statusf(format string, a ...any) {
+	fmt.Fprintf(p.Status, format, a...)
+}
+
+// ReleaseView is the schema used to render a release in structured outputs.
+// Fields are only ever added to it, never renamed or removed.
+type ReleaseView struct {
+	Tag             string    `json:"tag" yaml:"tag"`
+	Version         string    `json:"version" yaml:"version"`
+	Channel         string    `json:"channel" yaml:"channel"`
+	Installed       bool      `json:"installed" yaml:"installed"`
+	Current         bool      `json:"current" yaml:"current"`
+	Path            string    `json:"path,omitempty" yaml:"path,omitempty"`
+	PublishedAt     time.Time `json:"published_at" yaml:"published_at"`
+	Asset           string    `json:"asset,omitempty" yaml:"asset,omitempty"`
+	AssetSize       int64     `json:"asset_size" yaml:"asset_size"`
+	PlatformAsset   bool      `json:"platform_asset" yaml:"platform_asset"`
+	InstallSize     int64     `json:"install_size,omitempty" yaml:"install_size,omitempty"`
+	Source          string    `json:"source,omitempty" yaml:"source,omitempty"`
+	Build           string    `json:"build,omitempty" yaml:"build,omitempty"`
+	UpdateAvailable bool      `json:"update_available,omitempty" yaml:"update_available,omitempty"`
+}
+
+// newReleaseView creates the view of a release. When installed, path is
+// where the release is installed and is used to read its install record.
+func newReleaseView(info *release.Info, path string, installed bool,
+	current bool) ReleaseView {
+	view := ReleaseView{
+		Tag:         info.TagName,
+		Version:     info.CleanTagName(),
+		Channel:     releaseChannel(info),
+		Installed:   installed,
+		Current:     current,
+		PublishedAt: info.PublishedAt,
+	}
+	if asset := platformAsset(info); asset != nil {
+		view.Asset = asset.Name
+		view.AssetSize = int64(asset.Size)
+		view.PlatformAsset = true
+		view.Source = installer.AssetUrl(info, asset)
+	}
+	if !installed {
+		return view
+	}
+	view.Path = path
+	if record, err := release.ReadInstallRecord(path); err == nil &&
+		info.Version().IsNightly() {
+		view.Build = record.Describe()
+		view.UpdateAvailable = record.OutdatedBy(info)
+	}
+	return view
+}
+
+// releaseChannel classifies the release as stable, nightly, prerelease or a
+// regular release.
+func releaseChannel(info *release.Info) string {
+	switch {
+	case info.Stable:
+		return release.ChannelStable
+	case info.Version().IsNightly():
+		return release.ChannelNightly
+	case info.Prerelease || info.Version().IsPrerelease():
+		return ChannelPrerelease
+	}
+	return ChannelRelease
+}
+
+// installedView creates the view of an installed directory that is not a
+// known release, like the nightly snapshot.
+func installedView(path string, name string, current bool) ReleaseView {
+	view := ReleaseView{
+		Version:   name,
+		Channel:   ChannelRelease,
+		Installed: true,
+		Current:   current,
+		Path:      filepath.Join(path, name),
+	}
+	if name == NightlySnapshot || name == release.ChannelNightly {
+		view.Channel = release.ChannelNightly
+	}
+	if record, err := release.ReadInstallRecord(view.Path); err == nil {
+		view.Tag = record.TagName
+		view.PublishedAt = record.PublishedAt
+		view.Asset = record.Asset
+		view.Build = record.Describe()
+	}
+	return view
+}
+
+// label returns the release version followed by its annotations as shown in
+// the table output, such as "0.11.5 (stable)".
+func (v ReleaseView) label() string {
+	markers := v.markers()
+	if len(markers) == 0 {
+		return v.Version
+	}
+	return fmt.Sprintf("%s (%s)", v.Version, strings.Join(markers, ", "))
+}
+
+// markers returns the notes shown next to the version, like the stable
+// channel or the build of releases published under a moving tag.
+func (v ReleaseView) markers() []string {
+	switch {
+	case v.Channel == release.ChannelStable:
+		return []string{"stable"}
+	case v.Build != "" && v.UpdateAvailable:
+		return []string{v.Build, "update available"}
+	case v.Build != "":
+		return []string{v.Build}
+	}
+	return nil
+}
+
+// marker returns the prefix used in the table output to flag the current
+// release.
+func (v ReleaseView) marker() string {
+	if v.Current {
+		return "* "
+	}
+	return "  "
+}
+
+// ListView is the result of the list command.
+type ListView struct {
+	Releases []ReleaseView `json:"releases" yaml:"releases"`
+}
+
+// CurrentView is the result of the current command. Current is nil when no
+// release is set as current.
+type CurrentView struct {
+	Current *ReleaseView `json:"current" yaml:"current"`
+}
+
+// OutdatedEntry describes how far behind an installed release is.
+type OutdatedEntry struct {
+	Version     string `json:"version" yaml:"version"`
+	Current     bool   `json:"current" yaml:"current"`
+	NewestPatch string `json:"newest_patch,omitempty" yaml:"newest_patch,omitempty"`
+	Stable      string `json:"stable,omitempty" yaml:"stable,omitempty"`
+	// Build is the newer build available for releases published under a
+	// moving tag, like nightly.
+	Build    string `json:"build,omitempty" yaml:"build,omitempty"`
+	Outdated bool   `json:"outdated" yaml:"outdated"`
+}
+
+// OutdatedView is the result of the outdated command.
+type OutdatedView struct {
+	Releases []OutdatedEntry `json:"releases" yaml:"releases"`
+}
+
+const (
+	StatusInstalled  = "installed"
+	StatusUpgraded   = "upgraded"
+	StatusUpToDate   = "up_to_date"
+	StatusRolledBack = "rolled_back"
+	StatusFailed     = "failed"
+)
+
+// InstallView is the result of the install and upgrade commands.
+type InstallView struct {
+	Status   string      `json:"status" yaml:"status"`
+	Release  ReleaseView `json:"release" yaml:"release"`
+	Previous string      `json:"previous,omitempty" yaml:"previous,omitempty"`
+	// Steps are the install steps run, in order.
+	Steps []StepResult `json:"steps,omitempty" yaml:"steps,omitempty"`
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/candango/nvimm/installer"
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/release"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// fixtureReleases loads the releases fixture and installs nightly, 0.11.5
+// and 0.10.3 into a temporary path with 0.11.5 set as current.
+func fixtureReleases(t *testing.T) (release.Releases, string) {
+	t.Helper()
+	hostOS, hostArch = "linux", "amd64"
+	t.Cleanup(func() {
+		hostOS, hostArch = runtime.GOOS, runtime.GOARCH
+	})
+
+	data, err := os.ReadFile(filepath.Join("testdata", "releases.json"))
+	if err != nil {
+		t.Fatalf("failed to read releases fixture: %v", err)
+	}
+	releases := release.Releases{}
+	err = releases.Process(data, "0.7.0", nil)
+	if err != nil {
+		t.Fatalf("failed to process releases: %v", err)
+	}
+
+	path := t.TempDir()
+	for _, name := range []string{"nightly", "0.11.5", "0.10.3"} {
+		if err := os.MkdirAll(filepath.Join(path, name), 0755); err != nil {
+			t.Fatalf("failed to create release dir: %v", err)
+		}
+	}
+	nightly, err := releases.Get("nightly")
+	if err != nil {
+		t.Fatalf("failed to get nightly: %v", err)
+	}
+	installed := *nightly
+	installed.PublishedAt = nightly.PublishedAt.Add(-24 * time.Hour)
+	installed.Body = "NVIM v0.12.0-dev-1700+g1a2b3c4d5"
+	record := release.NewInstallRecord(&installed, &nightly.Assets[0],
+		nightly.PublishedAt)
+	if err := record.Write(filepath.Join(path, "nightly")); err != nil {
+		t.Fatalf("failed to write install record: %v", err)
+	}
+	if err := setCurrent(path, "0.11.5"); err != nil {
+		t.Fatal(err)
+	}
+	return releases, path
+}
+
+// assertGolden compares the output with the golden file, replacing the
+// temporary install path so the output is stable.
+func assertGolden(t *testing.T, name string, path string, got []byte) {
+	t.Helper()
+	got = []byte(strings.ReplaceAll(string(got), path, "/nvimm"))
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	assert.Equal(t, string(expected), string(got))
+}
+
+func TestOutput(t *testing.T) {
+	releases, path := fixtureReleases(t)
+
+	for _, format := range []string{OutputTable, OutputJSON, OutputYAML} {
+		t.Run("should render list as "+format, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			p := &Printer{Format: format, Out: out, Status: out}
+			view := listView(&releases, path, "0.11.5")
+			err := p.Render(view, func(w io.Writer) error {
+				return writeListTable(w, view, true)
+			})
+			assert.NoError(t, err)
+			assertGolden(t, "list."+format, path, out.Bytes())
+		})
+
+		t.Run("should render current as "+format, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			p := &Printer{Format: format, Out: out, Status: out}
+			current := currentView(&releases, path, "0.11.5")
+			view := CurrentView{Current: &current}
+			err := p.Render(view, func(w io.Writer) error {
+				return writeCurrentTable(w, view)
+			})
+			assert.NoError(t, err)
+			assertGolden(t, "current."+format, path, out.Bytes())
+		})
+
+		t.Run("should render outdated as "+format, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			p := &Printer{Format: format, Out: out, Status: out}
+			stable, err := releases.Get("stable")
+			assert.NoError(t, err)
+			view := OutdatedView{}
+			for _, info := range releases.Installed(path) {
+				entry := outdatedEntry(installer.New(path, ""), releases,
+					&info, stable)
+				entry.Current = info.CleanTagName() == "0.11.5"
+				view.Releases = append(view.Releases, entry)
+			}
+			err = p.Render(view, func(w io.Writer) error {
+				return writeOutdatedTable(w, view)
+			})
+			assert.NoError(t, err)
+			assertGolden(t, "outdated."+format, path, out.Bytes())
+		})
+	}
+
+	for _, format := range []string{OutputTable, OutputJSON} {
+		t.Run("should render info as "+format, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			p := &Printer{Format: format, Out: out, Status: out}
+			info, err := releases.Get("0.11.5")
+			assert.NoError(t, err)
+			view := newInfoView(info, filepath.Join(path, "0.11.5"), true,
+				true)
+			err = p.Render(view, func(w io.Writer) error {
+				return writeInfoTable(w, view)
+			})
+			assert.NoError(t, err)
+			assertGolden(t, "info."+format, path, out.Bytes())
+		})
+	}
+
+	for _, format := range []string{OutputTable, OutputJSON} {
+		t.Run("should render changelog as "+format, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			p := &Printer{Format: format, Out: out, Status: out}
+			from, err := releases.Get("0.10.3")
+			assert.NoError(t, err)
+			to, err := releases.Get("stable")
+			assert.NoError(t, err)
+			view := newChangelogView(releases, from, to, false)
+			err = p.Render(view, func(w io.Writer) error {
+				return writeChangelogTable(w, view)
+			})
+			assert.NoError(t, err)
+			assertGolden(t, "changelog."+format, path, out.Bytes())
+		})
+	}
+
+	t.Run("should keep only breaking changes and deprecations", func(t *testing.T) {
+		from, err := releases.Get("0.10.3")
+		assert.NoError(t, err)
+		to, err := releases.Get("stable")
+		assert.NoError(t, err)
+		view := newChangelogView(releases, from, to, true)
+		assert.Len(t, view.Releases, 2)
+		for _, entry := range view.Releases {
+			for _, section := range entry.Sections {
+				assert.NotEmpty(t, section.Kind, section.Title)
+			}
+		}
+		assert.True(t, view.Releases[0].Breaking)
+		assert.True(t, view.Releases[1].Deprecations)
+	})
+
+	t.Run("should flag current and stable releases in the picker", func(t *testing.T) {
+		view := listView(&releases, path, "0.11.5")
+		items := pickItems(view.Releases[1:3])
+		assert.Equal(t, "0.11.5", items[0].Label)
+		assert.Equal(t, "current, stable", items[0].Detail)
+		assert.Equal(t, "0.10.3", items[1].Label)
+		assert.Equal(t, "", items[1].Detail)
+	})
+
+	t.Run("should filter and render the long list", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := &ListCommand{Long: true, Limit: 3}
+		view := cmd.filter(listView(&releases, path, "0.11.5"))
+		assert.Len(t, view.Releases, 3)
+		assert.NoError(t, writeListLongTable(out, view))
+		assertGolden(t, "list.long.table", path, out.Bytes())
+
+		cmd = &ListCommand{Available: true}
+		view = cmd.filter(listView(&releases, path, "0.11.5"))
+		out.Reset()
+		assert.NoError(t, writeListTable(out, view, false))
+		assert.Equal(t, "Available versions\n  0.10.4\n", out.String())
+	})
+
+	t.Run("should send status to stderr in structured formats", func(t *testing.T) {
+		p := NewPrinter(&config.AppOptions{Output: OutputJSON})
+		assert.Equal(t, os.Stderr, p.Status)
+		p = NewPrinter(&config.AppOptions{Output: OutputTable})
+		assert.Equal(t, os.Stdout, p.Status)
+	})
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/candango/nvimm/internal/ui"
+)
+
+// defaultPager is used when the PAGER environment variable is not set. The
+// flags make less exit when the output fits the screen and keep the
+// terminal contents after quitting.
+const defaultPager = "less -FRX"
+
+// pagerCommand returns the command used to page long outputs, taken from the
+// PAGER environment variable. It returns nil if the pager is not available.
+func pagerCommand() []string {
+	pager := strings.TrimSpace(os.Getenv("PAGER"))
+	if pager == "" {
+		pager = defaultPager
+	}
+	args := strings.Fields(pager)
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return nil
+	}
+	return args
+}
+
+// page calls render to write the output to out through the pager when out is
+// a terminal, otherwise the output is written to out directly. The output is
+// also written directly if the pager cannot be started.
+func page(out io.Writer, render func(w io.Writer) error) error {
+	if !ui.IsTerminal(out) {
+		return render(out)
+	}
+	args := pagerCommand()
+	if args == nil {
+		return render(out)
+	}
+	buf := &bytes.Buffer{}
+	if err := render(buf); err != nil {
+		return err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(buf.Bytes())
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		_, err = buf.WriteTo(out)
+		return err
+	}
+	return cmd.Wait()
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/internal/ui"
+)
+
+// pickRelease asks the user to pick one of the releases, flagging the
+// current and the stable releases like the list command does. The picker is
+// drawn to the standard error so it stays visible in structured outputs.
+func pickRelease(opts *config.AppOptions, prompt string,
+	releases []ReleaseView) (string, error) {
+	u := ui.New(os.Stderr, opts.NoColor)
+	i, err := u.Pick(os.Stdin, prompt, pickItems(releases))
+	if err != nil {
+		return "", err
+	}
+	return releases[i].Version, nil
+}
+
+// pickItems converts the releases to the items shown by the picker.
+func pickItems(releases []ReleaseView) []ui.Item {
+	items := []ui.Item{}
+	for _, rv := range releases {
+		markers := rv.markers()
+		if rv.Current {
+			markers = append([]string{"current"}, markers...)
+		}
+		items = append(items, ui.Item{
+			Label:  rv.Version,
+			Detail: strings.Join(markers, ", "),
+		})
+	}
+	return items
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/internal/ui"
+	"github.com/candango/nvimm/release"
+)
+
+// ExitError carries the exit code of a process run by nvimm, which nvimm
+// exits with.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+type RunCommand struct {
+	Install bool `short:"i" long:"install" description:"Install the release first when it is not installed"`
+	appOpts *config.AppOptions
+}
+
+func (cmd *RunCommand) Usage() string {
+	return "<release> [-- nvim arguments]"
+}
+
+func (cmd *RunCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("positional argument release was not informed\n")
+	}
+	_, bin, err := installedNvim(cmd.appOpts, args[0], cmd.Install)
+	if err != nil {
+		return err
+	}
+	c := exec.Command(bin, args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	code, err := runForwardingSignals(c)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+func (cmd *RunCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+type WhichCommand struct {
+	appOpts *config.AppOptions
+}
+
+func (cmd *WhichCommand) Usage() string {
+	return "<release>"
+}
+
+func (cmd *WhichCommand) Execute(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("positional argument release was not informed\n")
+	}
+	name, bin, err := installedNvim(cmd.appOpts, args[0], false)
+	if err != nil {
+		return err
+	}
+	view := WhichView{Release: name, Path: bin}
+	return NewPrinter(cmd.appOpts).Render(view, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, view.Path)
+		return err
+	})
+}
+
+func (cmd *WhichCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
+
+// WhichView is the result of the which command.
+type WhichView struct {
+	Release string `json:"release" yaml:"release"`
+	Path    string `json:"path" yaml:"path"`
+}
+
+// installedNvim returns the name and the nvim binary of the installed
+// release matching the query. The query may name an install directory, like
+// "nightly.previous", or be resolved against the installed releases. If
+// nothing installed matches and install is true, the release resolved from
+// every release is installed, without being set as current.
+func installedNvim(appOpts *config.AppOptions, query string,
+	install bool) (string, string, error) {
+	binPath := func(name string) string {
+		return filepath.Join(appOpts.Path, name, "bin", nvimBinary())
+	}
+	if !strings.ContainsAny(query, `/\`) && isExecutable(binPath(query)) {
+		return query, binPath(query), nil
+	}
+
+	releases, err := loadReleases(appOpts)
+	if err != nil {
+		return "", "", err
+	}
+	installed := release.Releases(releases.Installed(appOpts.Path))
+	inst := newInstaller(appOpts)
+	selected, _, err := inst.Resolve(installed, query)
+	if err == nil {
+		name := selected.CleanTagName()
+		if !isExecutable(binPath(name)) {
+			return "", "", fmt.Errorf("release %s is installed without an "+
+				"nvim binary, run 'nvimm doctor'", name)
+		}
+		return name, binPath(name), nil
+	}
+	if !install {
+		return "", "", fmt.Errorf("no installed release matches %s, install "+
+			"it with 'nvimm install %s' or use --install", query, query)
+	}
+
+	// The standard output belongs to nvim, report the progress to stderr.
+	p := &Printer{Format: OutputTable, Out: os.Stderr, Status: os.Stderr,
+		UI: ui.New(os.Stderr, appOpts.NoColor)}
+	if appOpts.Quiet {
+		p.Status = io.Discard
+		p.UI = &ui.UI{Out: io.Discard}
+	}
+	steps := newInstallSteps(p)
+	var info *release.Info
+	err = steps.run(StepResolve, "", func(r *StepResult) error {
+		info, _, err = inst.Resolve(releases, query)
+		if err != nil {
+			return err
+		}
+		r.Message = fmt.Sprintf("Resolved %s to %s.", query,
+			info.CleanTagName())
+		return nil
+	})
+	if err != nil {
+		p.Statusf("%s\n", steps.summary(query))
+		return "", "", err
+	}
+	name := info.CleanTagName()
+	unlock, err := lockPaths(appOpts)
+	if err != nil {
+		return "", "", err
+	}
+	defer unlock()
+	// Another nvimm may have installed the release while the lock was
+	// waited for.
+	if isExecutable(binPath(name)) {
+		steps.skip(StepPlace, fmt.Sprintf("Release %s is already "+
+			"installed at %s.", name, inst.Path(name)))
+		p.Statusf("%s\n", steps.summary(name))
+		return name, binPath(name), nil
+	}
+	if _, err := installRelease(appOpts.Context(), steps, inst, info); err != nil {
+		os.RemoveAll(inst.Path(name))
+		p.Statusf("%s\n", steps.summary(name))
+		return "", "", err
+	}
+	steps.skip(StepActivate, "Current release not changed.")
+	p.Statusf("%s\n", steps.summary(name))
+	return name, binPath(name), nil
+}
+
+// runForwardingSignals runs the command, forwarding the signals nvimm
+// receives while it runs, and returns its exit code. A process killed by a
+// signal returns 128 plus the signal number, like shells do.
+func runForwardingSignals(c *exec.Cmd) (int, error) {
+	if err := c.Start(); err != nil {
+		return 0, fmt.Errorf("failed to run %s: %w", c.Path, err)
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, forwardedSignals...)
+	defer signal.Stop(sigs)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				c.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := c.Wait()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return 0, err
+	}
+	return exitCode(c.ProcessState), nil
+}
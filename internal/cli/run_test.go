@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake nvim binaries are shell scripts")
+	}
+
+	t.Run("should resolve installed releases", func(t *testing.T) {
+		opts := fixtureSetup(t, []string{"0.11.5", "0.10.3", "nightly.previous"},
+			nil)
+		data, err := os.ReadFile(filepath.Join("testdata", "releases.json"))
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(opts.CachePath,
+			"nvimm_releases.json"), data, 0644))
+
+		name, bin, err := installedNvim(opts, "nightly.previous", false)
+		assert.NoError(t, err)
+		assert.Equal(t, "nightly.previous", name)
+		assert.Equal(t, filepath.Join(opts.Path, "nightly.previous", "bin",
+			"nvim"), bin)
+
+		name, _, err = installedNvim(opts, "0.10", false)
+		assert.NoError(t, err)
+		assert.Equal(t, "0.10.3", name)
+
+		name, _, err = installedNvim(opts, "stable", false)
+		assert.NoError(t, err)
+		assert.Equal(t, "0.11.5", name)
+
+		_, _, err = installedNvim(opts, "0.9", false)
+		assert.ErrorContains(t, err, "no installed release matches 0.9")
+
+		_, _, err = installedNvim(opts, "../0.11.5", false)
+		assert.Error(t, err)
+
+		_, err = os.Lstat(filepath.Join(opts.Path, "current"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("should pass on arguments and the exit status", func(t *testing.T) {
+		bin := filepath.Join(t.TempDir(), "nvim")
+		assert.NoError(t, os.WriteFile(bin,
+			[]byte("#!/bin/sh\necho \"$@\"\nexit 4\n"), 0755))
+		out := &bytes.Buffer{}
+		c := exec.Command(bin, "--headless", "-c", "qa")
+		c.Stdout = out
+		code, err := runForwardingSignals(c)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, code)
+		assert.Equal(t, "--headless -c qa\n", out.String())
+	})
+
+	t.Run("should report death by signal", func(t *testing.T) {
+		bin := filepath.Join(t.TempDir(), "nvim")
+		assert.NoError(t, os.WriteFile(bin,
+			[]byte("#!/bin/sh\nkill -TERM $$\n"), 0755))
+		code, err := runForwardingSignals(exec.Command(bin))
+		assert.NoError(t, err)
+		assert.Equal(t, 143, code)
+	})
+
+	t.Run("should fail to run a missing binary", func(t *testing.T) {
+		_, err := runForwardingSignals(exec.Command(
+			filepath.Join(t.TempDir(), "nvim")))
+		assert.ErrorContains(t, err, "failed to run")
+	})
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardedSignals are passed on to the processes run by nvimm.
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM,
+	syscall.SIGHUP, syscall.SIGQUIT}
+
+// exitCode returns the exit code of the process, or 128 plus the signal
+// number if it was killed by a signal.
+func exitCode(state *os.ProcessState) int {
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return state.ExitCode()
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package cli
+
+import (
+	"os"
+)
+
+// forwardedSignals are passed on to the processes run by nvimm.
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// exitCode returns the exit code of the process.
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
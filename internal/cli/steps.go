@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/candango/nvimm/internal/ui"
+)
+
+// The steps of an install, in the order they run.
+const (
+	StepResolve  = "resolve"
+	StepDownload = "download"
+	StepVerify   = "verify"
+	StepExtract  = "extract"
+	StepPlace    = "place"
+	StepActivate = "activate"
+)
+
+// StepResult is the outcome of an install step, one of the ui outcomes, with
+// a message describing what happened and the time it took in seconds.
+type StepResult struct {
+	Step    string  `json:"step" yaml:"step"`
+	Outcome string  `json:"outcome" yaml:"outcome"`
+	Message string  `json:"message" yaml:"message"`
+	Time    float64 `json:"time" yaml:"time"`
+}
+
+// installSteps runs the steps of an install, reporting each outcome as it
+// finishes and recording it for the structured output and the summary.
+type installSteps struct {
+	p       *Printer
+	results []StepResult
+	// now is replaced by tests to get stable timings.
+	now func() time.Time
+}
+
+func newInstallSteps(p *Printer) *installSteps {
+	return &installSteps{p: p, results: []StepResult{}, now: time.Now}
+}
+
+// run runs fn as the step, showing a spinner with msg unless msg is empty,
+// when fn reports its own progress. The step succeeds unless fn returns an
+// error or sets another outcome in the result, whose message is reported.
+func (s *installSteps) run(step string, msg string,
+	fn func(r *StepResult) error) error {
+	start := s.now()
+	var spinner *ui.Spinner
+	if msg != "" {
+		spinner = s.p.Terminal().NewSpinner(msg)
+		spinner.Start()
+	}
+	r := StepResult{Step: step, Outcome: ui.OutcomeOK}
+	err := fn(&r)
+	if err != nil {
+		r.Outcome = ui.OutcomeFail
+		r.Message = err.Error()
+	}
+	r.Time = s.now().Sub(start).Round(time.Millisecond).Seconds()
+	line := fmt.Sprintf("%s %s", r.Message, s.p.Terminal().Dim(
+		fmt.Sprintf("(%s)", secondsString(r.Time))))
+	if spinner != nil {
+		spinner.Stop(r.Outcome, line)
+	} else {
+		s.p.Terminal().Report(r.Outcome, line)
+	}
+	s.results = append(s.results, r)
+	return err
+}
+
+// skip records the step as skipped, msg telling why.
+func (s *installSteps) skip(step string, msg string) {
+	s.p.Terminal().Report(ui.OutcomeSkip, msg)
+	s.results = append(s.results, StepResult{Step: step,
+		Outcome: ui.OutcomeSkip, Message: msg})
+}
+
+// summary describes the install of the release from the steps run so far,
+// like "Installed 0.11.5 in 4.2s: 5 ok, 1 skipped".
+func (s *installSteps) summary(name string) string {
+	total := 0.0
+	counts := map[string]int{}
+	failed := ""
+	for _, r := range s.results {
+		total += r.Time
+		counts[r.Outcome]++
+		if r.Outcome == ui.OutcomeFail && failed == "" {
+			failed = r.Step
+		}
+	}
+	parts := []string{}
+	for _, outcome := range []struct{ key, label string }{
+		{ui.OutcomeOK, "ok"},
+		{ui.OutcomeWarn, "warned"},
+		{ui.OutcomeFail, "failed"},
+		{ui.OutcomeSkip, "skipped"},
+	} {
+		if counts[outcome.key] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[outcome.key],
+				outcome.label))
+		}
+	}
+	if failed != "" {
+		return fmt.Sprintf("Install of %s failed at %s after %s: %s", name,
+			failed, secondsString(total), strings.Join(parts, ", "))
+	}
+	return fmt.Sprintf("Installed %s in %s: %s", name, secondsString(total),
+		strings.Join(parts, ", "))
+}
+
+// secondsString formats seconds as a duration, like "1.25s".
+func secondsString(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).String()
+}
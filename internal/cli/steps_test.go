@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/candango/nvimm/internal/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallSteps(t *testing.T) {
+	newSteps := func(out *bytes.Buffer) *installSteps {
+		steps := newInstallSteps(&Printer{Format: OutputTable, Out: out,
+			Status: out, UI: &ui.UI{Out: out}})
+		clock := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+		steps.now = func() time.Time {
+			clock = clock.Add(250 * time.Millisecond)
+			return clock
+		}
+		return steps
+	}
+
+	t.Run("should report the outcome of each step", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		steps := newSteps(out)
+		assert.NoError(t, steps.run(StepResolve, "", func(r *StepResult) error {
+			r.Message = "Resolved 0.10 to 0.10.4."
+			return nil
+		}))
+		assert.NoError(t, steps.run(StepVerify, "Calculating SHA256 checksum...",
+			func(r *StepResult) error {
+				r.Outcome = ui.OutcomeWarn
+				r.Message = "No checksum published, verification skipped."
+				return nil
+			}))
+		steps.skip(StepActivate, "Current release not changed.")
+		assert.Equal(t, "Resolved 0.10 to 0.10.4. (250ms) [OK]\n"+
+			"Calculating SHA256 checksum...\n"+
+			"No checksum published, verification skipped. (250ms) [WARN]\n"+
+			"Current release not changed. [SKIP]\n", out.String())
+		assert.Equal(t, "Installed 0.10.4 in 500ms: 1 ok, 1 warned, 1 skipped",
+			steps.summary("0.10.4"))
+		assert.Equal(t, []StepResult{
+			{Step: StepResolve, Outcome: ui.OutcomeOK,
+				Message: "Resolved 0.10 to 0.10.4.", Time: 0.25},
+			{Step: StepVerify, Outcome: ui.OutcomeWarn,
+				Message: "No checksum published, verification skipped.",
+				Time:    0.25},
+			{Step: StepActivate, Outcome: ui.OutcomeSkip,
+				Message: "Current release not changed."},
+		}, steps.results)
+	})
+
+	t.Run("should report failures", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		steps := newSteps(out)
+		err := steps.run(StepExtract, "Extracting archive...",
+			func(r *StepResult) error {
+				return errors.New("extraction failed: unexpected EOF")
+			})
+		assert.EqualError(t, err, "extraction failed: unexpected EOF")
+		assert.Equal(t, "Extracting archive...\n"+
+			"extraction failed: unexpected EOF (250ms) [FAIL]\n", out.String())
+		assert.NotContains(t, out.String(), "[OK]")
+		assert.Equal(t, "Install of 0.10.4 failed at extract after 250ms: "+
+			"1 failed", steps.summary("0.10.4"))
+	})
+}
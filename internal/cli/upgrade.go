@@ -0,0 +1,343 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/candango/iook/pathx"
+	"github.com/candango/nvimm/installer"
+	"github.com/candango/nvimm/internal/config"
+	"github.com/candango/nvimm/release"
+)
+
+// NightlySnapshot is the directory, relative to the install path, where the
+// nightly build replaced by the last upgrade is kept for rollbacks.
+const NightlySnapshot = "nightly.previous"
+
+// ExitUpToDate is the exit status used when an upgrade finds nothing newer to
+// install, allowing scripts to tell it apart from success and failure.
+const ExitUpToDate = 3
+
+// ErrUpToDate is returned by commands that had nothing to upgrade.
+var ErrUpToDate = errors.New("already up to date")
+
+type UpgradeCommand struct {
+	MinorLine bool `long:"minor-line" description:"Upgrade to the newest patch of the current minor line"`
+	ToStable  bool `long:"to-stable" description:"Upgrade to the release tagged as stable"`
+	RemoveOld bool `long:"remove-old" description:"Remove the replaced release after switching"`
+	Rollback  bool `long:"rollback" description:"Swap the installed nightly with the snapshot kept by the last upgrade"`
+	appOpts   *config.AppOptions
+	p         *Printer
+	steps     *installSteps
+}
+
+func (cmd *UpgradeCommand) Usage() string {
+	return "[--minor-line|--to-stable] [nightly]"
+}
+
+func (cmd *UpgradeCommand) Execute(args []string) error {
+	if !pathx.Exists(cmd.appOpts.Path) {
+		return fmt.Errorf("nvim path does not exist: %s",
+			cmd.appOpts.Path)
+	}
+	if cmd.MinorLine && cmd.ToStable {
+		return fmt.Errorf("--minor-line and --to-stable are mutually exclusive")
+	}
+	if len(args) > 0 && args[0] != release.ChannelNightly {
+		return fmt.Errorf("only the nightly release can be upgraded by "+
+			"name, %s was informed", args[0])
+	}
+
+	unlock, err := lockPaths(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	cmd.p = NewPrinter(cmd.appOpts)
+	cmd.steps = newInstallSteps(cmd.p)
+	current, err := currentRelease(cmd.appOpts.Path)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 || (current == release.ChannelNightly &&
+		!cmd.MinorLine && !cmd.ToStable) {
+		if cmd.Rollback && cmd.RemoveOld {
+			return fmt.Errorf("--rollback and --remove-old are mutually " +
+				"exclusive, the rollback keeps the replaced build as the " +
+				"snapshot")
+		}
+		if cmd.Rollback {
+			return cmd.rollbackNightly()
+		}
+		return cmd.upgradeNightly()
+	}
+	if cmd.Rollback {
+		return fmt.Errorf("--rollback is only supported for nightly")
+	}
+	if current == "" {
+		return fmt.Errorf("no current version set")
+	}
+	return cmd.upgradeCurrent(current)
+}
+
+// upgradeCurrent installs the newest release allowed by the command flags
+// when it is newer than the current one and sets it as current.
+func (cmd *UpgradeCommand) upgradeCurrent(current string) error {
+	currentVersion, err := release.ParseVersion(current)
+	if err != nil {
+		return fmt.Errorf("the current release %s cannot be upgraded: %w",
+			current, err)
+	}
+
+	query := release.AliasLatest
+	switch {
+	case cmd.MinorLine && currentVersion.IsNightly():
+		return fmt.Errorf("nightly has no minor line to follow")
+	case cmd.MinorLine:
+		query = fmt.Sprintf("%d.%d", currentVersion.Major,
+			currentVersion.Minor)
+	case cmd.ToStable:
+		query = release.ChannelStable
+	}
+
+	releases, err := loadReleases(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	var info *release.Info
+	inst := newInstaller(cmd.appOpts)
+	err = cmd.steps.run(StepResolve, "", func(r *StepResult) error {
+		info, _, err = inst.Resolve(releases, query)
+		if err != nil {
+			return fmt.Errorf("no release to upgrade %s to: %w", current, err)
+		}
+		r.Message = fmt.Sprintf("Resolved %s to %s.", query,
+			info.CleanTagName())
+		return nil
+	})
+	if err != nil {
+		return cmd.failed(current, nil, "", err)
+	}
+	target := info.Version()
+	if !currentVersion.IsNightly() && !currentVersion.Less(target) {
+		return cmd.upToDate(currentView(&releases, cmd.appOpts.Path,
+			current), current)
+	}
+
+	releaseName := info.CleanTagName()
+	releasePath := inst.Path(releaseName)
+	if !pathx.Exists(releasePath) {
+		if _, err := installRelease(cmd.appOpts.Context(), cmd.steps, inst,
+			info); err != nil {
+			os.RemoveAll(releasePath)
+			return cmd.failed(releaseName, info, current, err)
+		}
+	} else {
+		cmd.steps.skip(StepPlace, fmt.Sprintf("Release %s is already "+
+			"installed at %s.", releaseName, releasePath))
+	}
+	err = cmd.steps.run(StepActivate, "", func(r *StepResult) error {
+		if err := inst.Activate(releaseName); err != nil {
+			return err
+		}
+		r.Message = fmt.Sprintf("Upgraded %s -> %s.", current, releaseName)
+		return nil
+	})
+	if err != nil {
+		return cmd.failed(releaseName, info, current, err)
+	}
+	cmd.p.Statusf("%s\n", cmd.steps.summary(releaseName))
+
+	if cmd.RemoveOld {
+		oldPath := filepath.Join(cmd.appOpts.Path, current)
+		if err := os.RemoveAll(oldPath); err != nil {
+			return fmt.Errorf("failed to remove release %s: %w", current, err)
+		}
+		cmd.p.Statusf("Removed %s\n", oldPath)
+	}
+	return cmd.render(StatusUpgraded, newReleaseView(info, releasePath, true,
+		true), current)
+}
+
+// render writes the upgrade result when a structured output was requested,
+// the table output is made of the progress messages already printed.
+func (cmd *UpgradeCommand) render(status string, view ReleaseView,
+	previous string) error {
+	if !cmd.p.Structured() {
+		return nil
+	}
+	return cmd.p.Render(InstallView{
+		Status:   status,
+		Release:  view,
+		Previous: previous,
+		Steps:    cmd.steps.results,
+	}, nil)
+}
+
+// failed reports the summary of the failed install of name and renders the
+// failed result, returning err.
+func (cmd *UpgradeCommand) failed(name string, info *release.Info,
+	previous string, err error) error {
+	cmd.p.Statusf("%s\n", cmd.steps.summary(name))
+	view := ReleaseView{}
+	if info != nil {
+		view = newReleaseView(info, "", false, false)
+	}
+	if rerr := cmd.render(StatusFailed, view, previous); rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+// upToDate renders the up to date result and returns ErrUpToDate.
+func (cmd *UpgradeCommand) upToDate(view ReleaseView, desc string) error {
+	if err := cmd.render(StatusUpToDate, view, ""); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrUpToDate, desc)
+}
+
+// upgradeNightly installs the newest nightly build when it differs from the
+// installed one, moving the installed build to the NightlySnapshot directory.
+// With --remove-old, the snapshot is removed once the upgrade succeeds.
+func (cmd *UpgradeCommand) upgradeNightly() error {
+	nightlyPath := filepath.Join(cmd.appOpts.Path, release.ChannelNightly)
+	if !pathx.Exists(nightlyPath) {
+		return fmt.Errorf("the release nightly is not installed")
+	}
+	releases, err := loadReleases(cmd.appOpts)
+	if err != nil {
+		return err
+	}
+	var info *release.Info
+	err = cmd.steps.run(StepResolve, "", func(r *StepResult) error {
+		info, err = releases.Get(release.ChannelNightly)
+		if err != nil {
+			return err
+		}
+		r.Message = fmt.Sprintf("Resolved nightly to the build published "+
+			"at %s.", info.PublishedAt.Format(time.DateTime))
+		return nil
+	})
+	if err != nil {
+		return cmd.failed(release.ChannelNightly, nil, "", err)
+	}
+
+	installed, err := release.ReadInstallRecord(nightlyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read nightly install record: %w", err)
+	}
+	if installed != nil && !installed.OutdatedBy(info) {
+		current, _ := currentRelease(cmd.appOpts.Path)
+		return cmd.upToDate(newReleaseView(info, nightlyPath, true,
+			current == release.ChannelNightly), "nightly "+
+			installed.Describe())
+	}
+
+	snapshotPath := filepath.Join(cmd.appOpts.Path, NightlySnapshot)
+	if err := os.RemoveAll(snapshotPath); err != nil {
+		return fmt.Errorf("failed to remove nightly snapshot: %w", err)
+	}
+	if err := os.Rename(nightlyPath, snapshotPath); err != nil {
+		return fmt.Errorf("failed to snapshot nightly: %w", err)
+	}
+
+	previous := "unknown build"
+	if installed != nil {
+		previous = installed.Describe()
+	}
+	record, err := installRelease(cmd.appOpts.Context(), cmd.steps,
+		newInstaller(cmd.appOpts), info)
+	if err != nil {
+		os.RemoveAll(nightlyPath)
+		if rerr := os.Rename(snapshotPath, nightlyPath); rerr != nil {
+			err = fmt.Errorf("failed to upgrade nightly: %w (restoring "+
+				"the previous build failed: %v)", err, rerr)
+		} else {
+			err = fmt.Errorf("failed to upgrade nightly, previous build "+
+				"restored: %w", err)
+		}
+		return cmd.failed(release.ChannelNightly, info, previous, err)
+	}
+
+	current, err := currentRelease(cmd.appOpts.Path)
+	if err != nil {
+		return err
+	}
+	if current == release.ChannelNightly {
+		cmd.steps.run(StepActivate, "", func(r *StepResult) error {
+			r.Message = fmt.Sprintf("Nightly upgraded in place: %s -> %s.",
+				previous, record.Describe())
+			return nil
+		})
+	} else {
+		cmd.steps.skip(StepActivate, fmt.Sprintf("Nightly upgraded: %s -> "+
+			"%s, current release not changed.", previous, record.Describe()))
+	}
+	if cmd.RemoveOld {
+		if err := os.RemoveAll(snapshotPath); err != nil {
+			return fmt.Errorf("failed to remove nightly snapshot: %w", err)
+		}
+		cmd.p.Statusf("Removed %s\n", snapshotPath)
+	} else {
+		cmd.p.Statusf("Previous build kept at: %s\n", snapshotPath)
+	}
+	cmd.p.Statusf("%s\n", cmd.steps.summary(release.ChannelNightly))
+	return cmd.render(StatusUpgraded, newReleaseView(info, nightlyPath, true,
+		current == release.ChannelNightly), previous)
+}
+
+// rollbackNightly swaps the installed nightly with the snapshot, so running
+// it twice restores the upgraded build.
+func (cmd *UpgradeCommand) rollbackNightly() error {
+	nightlyPath := filepath.Join(cmd.appOpts.Path, release.ChannelNightly)
+	snapshotPath := filepath.Join(cmd.appOpts.Path, NightlySnapshot)
+	if !pathx.Exists(snapshotPath) {
+		return fmt.Errorf("there is no nightly snapshot to roll back to")
+	}
+	swapPath := nightlyPath + ".swap"
+	if pathx.Exists(nightlyPath) {
+		if err := os.Rename(nightlyPath, swapPath); err != nil {
+			return fmt.Errorf("failed to move nightly aside: %w", err)
+		}
+	}
+	if err := os.Rename(snapshotPath, nightlyPath); err != nil {
+		os.Rename(swapPath, nightlyPath)
+		return fmt.Errorf("failed to restore nightly snapshot: %w", err)
+	}
+	if pathx.Exists(swapPath) {
+		if err := os.Rename(swapPath, snapshotPath); err != nil {
+			return fmt.Errorf("failed to keep replaced nightly: %w", err)
+		}
+	}
+	if record, err := release.ReadInstallRecord(nightlyPath); err == nil {
+		cmd.p.Statusf("Nightly rolled back to %s\n", record.Describe())
+	} else {
+		cmd.p.Statusf("Nightly rolled back.\n")
+	}
+	current, err := currentRelease(cmd.appOpts.Path)
+	if err != nil {
+		return err
+	}
+	view := installedView(cmd.appOpts.Path, release.ChannelNightly,
+		current == release.ChannelNightly)
+	return cmd.render(StatusRolledBack, view, "")
+}
+
+// currentRelease returns the name of the release the current symlink points
+// to, or an empty string if no release is set as current.
+func currentRelease(path string) (string, error) {
+	return installer.New(path, "").Current()
+}
+
+// setCurrent points the current symlink to the installed release.
+func setCurrent(path string, releaseName string) error {
+	return installer.New(path, "").Activate(releaseName)
+}
+
+func (cmd *UpgradeCommand) SetAppOptions(opts *config.AppOptions) {
+	cmd.appOpts = opts
+}
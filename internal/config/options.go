@@ -1,28 +1,149 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/candango/iook/pathx"
+	"github.com/candango/nvimm/internal/cache"
+	"github.com/candango/nvimm/internal/logging"
+	"github.com/candango/nvimm/internal/protocol"
 	"github.com/jessevdk/go-flags"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	CacheBackendFile  = "file"
+	CacheBackendRedis = "redis"
 )
 
 type AppOptions struct {
-	Verbose        bool   `short:"v" long:"verbose" description:"Enable verbose mode"`
-	CachePath      string `short:"C" long:"cache-path" env:"NVIMM_CACHE_PATH" description:"Cache directory"`
-	ConfigPath     string `short:"c" long:"config" env:"NVIMM_CONFIG_PATH" description:"Configuration file path"`
-	ConfigDir      string `short:"d" long:"config-dir" env:"NVIMM_CONFIG_DIR" description:"Configuration file directory"`
-	ConfigFileName string `short:"n" long:"config-file-name" env:"NVIMM_CONFIG_FILE_NAME" default:"nvimm.yml" description:"Configuration file name"`
-	Path           string `short:"p" long:"path" env:"NVIMM_PATH" description:"Path where Neovim releases are installed"`
-	MinRelease     string `short:"r" long:"min-release" env:"NVIMM_MIN_RELEASE" default:"0.7.0" description:"Neovim minimal release"`
+	Verbose        []bool        `short:"v" long:"verbose" description:"Enable verbose mode, repeat (-vv) for debug messages"`
+	Quiet          bool          `short:"q" long:"quiet" description:"Only print results and errors"`
+	LogFile        string        `long:"log-file" env:"NVIMM_LOG_FILE" description:"Write log messages to this file instead of the standard error"`
+	NoColor        bool          `long:"no-color" description:"Disable colors, also disabled by setting NO_COLOR"`
+	LogFormat      string        `long:"log-format" env:"NVIMM_LOG_FORMAT" default:"text" choice:"text" choice:"json" description:"Log message format"`
+	CachePath      string        `short:"C" long:"cache-path" env:"NVIMM_CACHE_PATH" description:"Cache directory"`
+	ConfigPath     string        `short:"c" long:"config" env:"NVIMM_CONFIG_PATH" description:"Configuration file path"`
+	ConfigDir      string        `short:"d" long:"config-dir" env:"NVIMM_CONFIG_DIR" description:"Configuration file directory"`
+	ConfigFileName string        `short:"n" long:"config-file-name" env:"NVIMM_CONFIG_FILE_NAME" default:"nvimm.yml" description:"Configuration file name"`
+	Path           string        `short:"p" long:"path" env:"NVIMM_PATH" description:"Path where Neovim releases are installed"`
+	MinRelease     string        `short:"r" long:"min-release" env:"NVIMM_MIN_RELEASE" default:"0.7.0" description:"Neovim minimal release"`
+	Output         string        `short:"o" long:"output" env:"NVIMM_OUTPUT" default:"table" choice:"table" choice:"json" choice:"yaml" description:"Output format"`
+	ApiUrl         string        `long:"api-url" env:"NVIMM_API_URL" default:"https://api.github.com" description:"GitHub API base URL"`
+	CheckUpdates   bool          `long:"check-updates" env:"NVIMM_CHECK_UPDATES" description:"Print a notice when a newer stable release is available"`
+	ConnectTimeout time.Duration `long:"connect-timeout" env:"NVIMM_CONNECT_TIMEOUT" default:"10s" description:"Maximum time to establish a connection, 0 disables it"`
+	ReadTimeout    time.Duration `long:"read-timeout" env:"NVIMM_READ_TIMEOUT" default:"30s" description:"Maximum time waiting for data from a connection, 0 disables it"`
+	Proxy          string        `long:"proxy" env:"NVIMM_PROXY" value-name:"url" description:"Proxy URL, credentials included, used instead of HTTPS_PROXY"`
+	CABundle       []string      `long:"ca-bundle" env:"NVIMM_CA_BUNDLE" env-delim:"," value-name:"file" description:"PEM file with extra certificate authorities to trust, may be repeated"`
+	ClientCert     string        `long:"client-cert" env:"NVIMM_CLIENT_CERT" value-name:"file" description:"PEM file with the client certificate for mutual TLS"`
+	ClientKey      string        `long:"client-key" env:"NVIMM_CLIENT_KEY" value-name:"file" description:"PEM file with the client certificate key, if not in the certificate file"`
+	Insecure       bool          `long:"insecure" env:"NVIMM_INSECURE" description:"Skip the verification of server certificates, unsafe"`
+	Retries        int           `long:"retries" env:"NVIMM_RETRIES" default:"3" description:"Times a request failing with a network error, 5xx or 429 status is retried, 0 disables retries"`
+	MaxBackoff     time.Duration `long:"max-backoff" env:"NVIMM_MAX_BACKOFF" default:"30s" description:"Maximum wait between retries"`
+	CacheBackend   string        `long:"cache-backend" env:"NVIMM_CACHE_BACKEND" default:"file" choice:"file" choice:"redis" description:"Where the releases listing is cached, redis shares it between machines"`
+	RedisUrl       string        `long:"redis-url" env:"NVIMM_REDIS_URL" default:"redis://localhost:6379/0" value-name:"url" description:"Redis server of the redis cache backend"`
+	RedisPrefix    string        `long:"redis-prefix" env:"NVIMM_REDIS_PREFIX" default:"nvimm:" description:"Prefix of the keys of the redis cache backend"`
+	LockTimeout    time.Duration `long:"lock-timeout" env:"NVIMM_LOCK_TIMEOUT" default:"1m" description:"Maximum wait for another nvimm changing the same directories to finish"`
+	// Logger is set by WithLogger, use Log to access it.
+	Logger *slog.Logger `no-flag:"true"`
+	// LogWriter is the LogFile opened by WithLogger, closed by WithAppOptions
+	// after the command runs.
+	LogWriter *os.File `no-flag:"true"`
+	// KeepMissingPaths is set by WithAppOptions for commands implementing
+	// PathsDiagnoser, WithPathsResolved does not create the paths then.
+	KeepMissingPaths bool `no-flag:"true"`
+	// Ctx is set by WithSignals, use Context to access it.
+	Ctx context.Context `no-flag:"true"`
+	// Client is set by WithHttpClient, use HttpClient to access it.
+	Client *http.Client `no-flag:"true"`
+	// Redis is set by WithCacheBackend when the redis backend is selected,
+	// use Cacher to access the cache.
+	Redis redis.UniversalClient `no-flag:"true"`
+}
+
+// Verbosity returns how many times the verbose flag was informed.
+func (opts *AppOptions) Verbosity() int {
+	return len(opts.Verbose)
+}
+
+// Log returns the logger created by WithLogger, or a logger discarding every
+// record if it was not created.
+func (opts *AppOptions) Log() *slog.Logger {
+	if opts.Logger == nil {
+		return logging.Discard()
+	}
+	return opts.Logger
+}
+
+// Context returns the context created by WithSignals, or a context never
+// canceled if it was not created.
+func (opts *AppOptions) Context() context.Context {
+	if opts.Ctx == nil {
+		return context.Background()
+	}
+	return opts.Ctx
+}
+
+// HttpClient returns the client created by WithHttpClient, or a client
+// without timeouts nor proxy settings if it was not created.
+func (opts *AppOptions) HttpClient() *http.Client {
+	if opts.Client == nil {
+		return &http.Client{Transport: logging.NewTransport(nil, opts.Log())}
+	}
+	return opts.Client
+}
+
+// RetryPolicy returns how failed requests and downloads are retried.
+func (opts *AppOptions) RetryPolicy() protocol.RetryPolicy {
+	return protocol.RetryPolicy{
+		Retries:    opts.Retries,
+		MaxBackoff: opts.MaxBackoff,
+	}
+}
+
+// Cacher returns the cache of the named data in the selected backend, a file
+// in the cache path by default or a key with the prefix in Redis.
+func (opts *AppOptions) Cacher(name string) cache.Cacher {
+	if opts.Redis != nil {
+		c := cache.NewRedisCacher(opts.Redis, opts.RedisPrefix+name)
+		c.Ctx = opts.Context()
+		c.Logger = opts.Log()
+		return c
+	}
+	c := cache.NewFileCacher(opts.CachePath, name)
+	c.Logger = opts.Log()
+	return c
+}
+
+// closeLog closes the log file opened by WithLogger, the logger is switched
+// to discard the records logged after it.
+func (opts *AppOptions) closeLog() {
+	if opts.LogWriter == nil {
+		return
+	}
+	opts.LogWriter.Close()
+	opts.LogWriter = nil
+	opts.Logger = nil
 }
 
 type AppOptionsAware interface {
 	SetAppOptions(opts *AppOptions)
 }
 
+// PathsDiagnoser is implemented by commands diagnosing the nvimm paths,
+// which must see them as they are instead of created by WithPathsResolved.
+type PathsDiagnoser interface {
+	DiagnosesPaths()
+}
+
 func WithError(err error) func(cmd flags.Commander, args []string) error {
 	return func(_ flags.Commander, _ []string) error {
 		return err
@@ -33,6 +154,7 @@ type AppOptionsFunc func(opts *AppOptions) error
 
 func WithAppOptions(opts *AppOptions, fns ...AppOptionsFunc) func(cmd flags.Commander, args []string) error {
 	return func(cmd flags.Commander, args []string) error {
+		defer opts.closeLog()
 		if opts.ConfigDir == "" {
 			userConfigDir, err := os.UserConfigDir()
 			if err != nil {
@@ -58,6 +180,10 @@ func WithAppOptions(opts *AppOptions, fns ...AppOptionsFunc) func(cmd flags.Comm
 			opts.CachePath = filepath.Join(userCacheDir, "nvimm")
 		}
 
+		if _, ok := cmd.(PathsDiagnoser); ok {
+			opts.KeepMissingPaths = true
+		}
+
 		// Apply extra functions
 		if len(fns) > 0 {
 			for _, fn := range fns {
@@ -75,7 +201,91 @@ func WithAppOptions(opts *AppOptions, fns ...AppOptionsFunc) func(cmd flags.Comm
 	}
 }
 
+// WithLogger creates the application logger with the level chosen by the
+// verbose and quiet flags. Records are written to the standard error, or
+// appended to LogFile when informed.
+func WithLogger(opts *AppOptions) error {
+	w := os.Stderr
+	if opts.LogFile != "" {
+		f, err := os.OpenFile(opts.LogFile,
+			os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("error opening nvimm log file %s: %w",
+				opts.LogFile, err)
+		}
+		w = f
+		opts.LogWriter = f
+	}
+	opts.Logger = logging.New(w, opts.LogFormat,
+		logging.Level(opts.Verbosity(), opts.Quiet))
+	return nil
+}
+
+// WithHttpClient creates the HTTP client with the timeouts, proxy, TLS and
+// retry settings of the options. Disabling the certificate verification prints a
+// warning to the standard error even in quiet mode.
+func WithHttpClient(opts *AppOptions) error {
+	client, err := protocol.NewClient(protocol.ClientOptions{
+		ConnectTimeout: opts.ConnectTimeout,
+		ReadTimeout:    opts.ReadTimeout,
+		Proxy:          opts.Proxy,
+		CABundles:      opts.CABundle,
+		ClientCert:     opts.ClientCert,
+		ClientKey:      opts.ClientKey,
+		Insecure:       opts.Insecure,
+		Retry:          opts.RetryPolicy(),
+	}, opts.Log())
+	if err != nil {
+		return fmt.Errorf("error creating the http client: %w", err)
+	}
+	if opts.Insecure {
+		fmt.Fprintln(os.Stderr, "WARNING: --insecure disables TLS "+
+			"certificate verification, downloads can be tampered with "+
+			"without notice. Prefer --ca-bundle with the certificate "+
+			"authority of your network.")
+		opts.Log().Warn("tls certificate verification disabled")
+	}
+	opts.Client = client
+	return nil
+}
+
+// WithCacheBackend connects to the Redis server when the redis cache backend
+// is selected. The connection is established on the first command sent.
+func WithCacheBackend(opts *AppOptions) error {
+	if opts.CacheBackend != CacheBackendRedis {
+		return nil
+	}
+	client, err := cache.NewRedisClient(opts.RedisUrl)
+	if err != nil {
+		return fmt.Errorf("error creating the redis client: %w", err)
+	}
+	opts.Redis = client
+	return nil
+}
+
+// WithSignals creates the application context, canceled when nvimm receives
+// SIGINT or SIGTERM so running downloads and extractions stop and clean up.
+// The signals are handled only once, another one terminates nvimm right
+// away.
+func WithSignals(opts *AppOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt,
+		syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	opts.Ctx = ctx
+	return nil
+}
+
+// WithPathsResolved creates the config directory and file, the path and the
+// cache path when missing, unless KeepMissingPaths is set.
 func WithPathsResolved(opts *AppOptions) error {
+	if opts.KeepMissingPaths {
+		opts.Log().Debug("paths kept as they are", "config",
+			opts.ConfigPath, "path", opts.Path, "cache", opts.CachePath)
+		return nil
+	}
 	if !pathx.Exists(opts.ConfigDir) {
 		err := os.MkdirAll(opts.ConfigDir, 0755)
 		if err != nil {
@@ -112,5 +322,7 @@ func WithPathsResolved(opts *AppOptions) error {
 				opts.CachePath, err)
 		}
 	}
+	opts.Log().Debug("paths resolved", "config", opts.ConfigPath,
+		"path", opts.Path, "cache", opts.CachePath)
 	return nil
 }
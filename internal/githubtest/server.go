@@ -0,0 +1,274 @@
+// Package githubtest provides a fake GitHub server for hermetic tests. It
+// serves a recorded releases listing through the API endpoints used by nvimm
+// and generated tarballs for the listed assets, with digests matching them.
+// Faults can be injected per asset and the API can be rate limited.
+package githubtest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Fault is a failure injected when an asset is downloaded.
+type Fault int
+
+const (
+	// FaultNone serves the asset as published.
+	FaultNone Fault = iota
+	// FaultNotFound answers 404 Not Found.
+	FaultNotFound
+	// FaultCorrupt serves bytes not matching the published digest.
+	FaultCorrupt
+	// FaultTruncate announces the full size but closes the connection after
+	// half of it.
+	FaultTruncate
+	// FaultStall sends half of the asset and stops sending data until the
+	// client gives up on the request.
+	FaultStall
+	// FaultUnavailable answers 503 Service Unavailable.
+	FaultUnavailable
+)
+
+// RateLimitReset is the reset time announced when the API is rate limited.
+var RateLimitReset = time.Date(2026, 10, 18, 15, 4, 5, 0, time.Local)
+
+// Server is a fake GitHub serving the API at URL and the release downloads
+// under URL/neovim/neovim/releases/download.
+type Server struct {
+	*httptest.Server
+	mu          sync.Mutex
+	releases    []byte
+	assets      map[string][]byte
+	faults      map[string]Fault
+	faultTimes  map[string]int
+	rateLimited bool
+	hits        map[string]int
+}
+
+// NewServer starts a server for the recorded releases listing. The release
+// and download URLs of the listing are rewritten to the server and a tarball
+// is generated for every .tar.gz asset, replacing the asset digest, the
+// checksums found in the release body and the size. The server is closed
+// when the test ends.
+func NewServer(t testing.TB, recorded []byte) *Server {
+	t.Helper()
+	s := &Server{
+		assets:     map[string][]byte{},
+		faults:     map[string]Fault{},
+		faultTimes: map[string]int{},
+		hits:       map[string]int{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/neovim/neovim/releases", s.handleReleases)
+	mux.HandleFunc("GET /rate_limit", s.handleRateLimit)
+	mux.HandleFunc("GET /neovim/neovim/releases/download/{tag}/{name}",
+		s.handleDownload)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(func() {
+		// Stalled downloads only end when their connection is closed.
+		s.CloseClientConnections()
+		s.Close()
+	})
+
+	releases := []map[string]any{}
+	if err := json.Unmarshal(recorded, &releases); err != nil {
+		t.Fatalf("failed to unmarshal the recorded releases: %v", err)
+	}
+	for _, rel := range releases {
+		tag, _ := rel["tag_name"].(string)
+		rel["html_url"] = s.URL + "/neovim/neovim/releases/tag/" + tag
+		body, _ := rel["body"].(string)
+		assets, _ := rel["assets"].([]any)
+		for _, a := range assets {
+			asset, ok := a.(map[string]any)
+			if !ok {
+				continue
+			}
+			name, _ := asset["name"].(string)
+			if !strings.HasSuffix(name, ".tar.gz") {
+				continue
+			}
+			data := Tarball(strings.TrimSuffix(name, ".tar.gz"), tag)
+			sum := fmt.Sprintf("%x", sha256.Sum256(data))
+			s.assets[tag+"/"+name] = data
+			asset["size"] = len(data)
+			asset["browser_download_url"] = fmt.Sprintf(
+				"%s/neovim/neovim/releases/download/%s/%s", s.URL, tag, name)
+			if digest, _ := asset["digest"].(string); digest != "" {
+				asset["digest"] = "sha256:" + sum
+			}
+			checksumRe := regexp.MustCompile(`[a-f0-9]{64}(\s+` +
+				regexp.QuoteMeta(name) + `)`)
+			body = checksumRe.ReplaceAllString(body, sum+"${1}")
+		}
+		if _, ok := rel["body"]; ok {
+			rel["body"] = body
+		}
+	}
+	data, err := json.Marshal(releases)
+	if err != nil {
+		t.Fatalf("failed to marshal the releases: %v", err)
+	}
+	s.releases = data
+	return s
+}
+
+// Tarball returns a gzipped tarball holding an nvim script, printing the
+// version like "nvim --version" does, under the top directory, like the
+// tarballs published by Neovim.
+func Tarball(top string, tag string) []byte {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	script := []byte(fmt.Sprintf("#!/bin/sh\necho \"NVIM %s\"\n", tag))
+	tw.WriteHeader(&tar.Header{Name: top + "/", Typeflag: tar.TypeDir,
+		Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: top + "/bin/", Typeflag: tar.TypeDir,
+		Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: top + "/bin/nvim",
+		Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(script))})
+	tw.Write(script)
+	tw.Close()
+	gzw.Close()
+	return buf.Bytes()
+}
+
+// Fail injects the fault when the asset of the release tag is downloaded.
+func (s *Server) Fail(tag string, name string, fault Fault) {
+	s.FailTimes(tag, name, fault, 0)
+}
+
+// FailTimes injects the fault in the next downloads of the asset of the
+// release tag, then serves it as published. Zero times injects it in every
+// download.
+func (s *Server) FailTimes(tag string, name string, fault Fault, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[tag+"/"+name] = fault
+	s.faultTimes[tag+"/"+name] = times
+}
+
+// SetRateLimited makes the API answer 403 Forbidden with an exhausted rate
+// limit, like GitHub does.
+func (s *Server) SetRateLimited(limited bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimited = limited
+}
+
+// SetReleases replaces the listing answered by the API, like an error
+// payload served with 200 OK by a misbehaving proxy.
+func (s *Server) SetReleases(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releases = data
+}
+
+// Hits returns how many requests were made to the path.
+func (s *Server) Hits(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+func (s *Server) hit(r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits[r.URL.Path]++
+}
+
+func (s *Server) limited(w http.ResponseWriter) bool {
+	s.mu.Lock()
+	limited := s.rateLimited
+	s.mu.Unlock()
+	remaining := "60"
+	if limited {
+		remaining = "0"
+	}
+	w.Header().Set("X-RateLimit-Limit", "60")
+	w.Header().Set("X-RateLimit-Remaining", remaining)
+	w.Header().Set("X-RateLimit-Reset",
+		strconv.FormatInt(RateLimitReset.Unix(), 10))
+	if limited {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+	}
+	return limited
+}
+
+func (s *Server) handleReleases(w http.ResponseWriter, r *http.Request) {
+	s.hit(r)
+	if s.limited(w) {
+		return
+	}
+	s.mu.Lock()
+	data := s.releases
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (s *Server) handleRateLimit(w http.ResponseWriter, r *http.Request) {
+	s.hit(r)
+	s.mu.Lock()
+	remaining := 60
+	if s.rateLimited {
+		remaining = 0
+	}
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"resources":{"core":{"limit":60,"remaining":%d,`+
+		`"reset":%d}}}`, remaining, RateLimitReset.Unix())
+}
+
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	s.hit(r)
+	key := r.PathValue("tag") + "/" + r.PathValue("name")
+	s.mu.Lock()
+	data, ok := s.assets[key]
+	fault := s.faults[key]
+	if times := s.faultTimes[key]; times > 0 {
+		if times == 1 {
+			delete(s.faults, key)
+		}
+		s.faultTimes[key] = times - 1
+	}
+	s.mu.Unlock()
+	if !ok || fault == FaultNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if fault == FaultUnavailable {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	switch fault {
+	case FaultCorrupt:
+		corrupt := bytes.Clone(data)
+		corrupt[len(corrupt)/2] ^= 0xff
+		w.Write(corrupt)
+	case FaultTruncate:
+		w.Write(data[:len(data)/2])
+	case FaultStall:
+		w.Write(data[:len(data)/2])
+		http.NewResponseController(w).Flush()
+		<-r.Context().Done()
+	default:
+		w.Write(data)
+	}
+}
@@ -0,0 +1,108 @@
+// Package lock provides advisory file locks keeping nvimm processes from
+// changing the same directories at the same time.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the lock file created in a locked directory.
+const FileName = ".nvimm.lock"
+
+// ErrLocked is returned when the lock is still held by another process after
+// the timeout.
+var ErrLocked = errors.New("another nvimm is running")
+
+// pollInterval is how often a held lock is tried again.
+var pollInterval = 100 * time.Millisecond
+
+// Lock is an advisory lock held on a directory. The lock is released by the
+// operating system when the holding process exits, so a crashed nvimm never
+// leaves a stale lock behind.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire locks the directory, waiting up to the timeout while another
+// process holds the lock, and writes the pid of the process to the lock
+// file. A zero timeout fails right away when the lock is held. Waiting stops
+// when the context is canceled.
+func Acquire(ctx context.Context, dir string, timeout time.Duration) (*Lock,
+	error) {
+	path := filepath.Join(dir, FileName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			file.Close()
+			return nil, lockedError(path, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, context.Cause(ctx)
+		case <-time.After(pollInterval):
+		}
+	}
+	pid := []byte(strconv.Itoa(os.Getpid()) + "\n")
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt(pid, 0)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Holder returns the pid written to the lock file of the directory, or zero
+// if it is unknown.
+func Holder(dir string) int {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// lockedError returns the error telling which process holds the lock.
+func lockedError(path string, timeout time.Duration) error {
+	holder := ""
+	if pid := Holder(filepath.Dir(path)); pid > 0 {
+		holder = fmt.Sprintf(" (pid %d)", pid)
+	}
+	return fmt.Errorf("%w%s, gave up after waiting %s for %s", ErrLocked,
+		holder, timeout, path)
+}
+
+// Release releases the lock. The lock file is kept, removing it would let
+// a process waiting on the removed file and a new one both hold a lock.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := unlock(l.file)
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,63 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	pollInterval = 5 * time.Millisecond
+
+	t.Run("should fail while another lock is held", func(t *testing.T) {
+		dir := t.TempDir()
+		l, err := Acquire(context.Background(), dir, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, os.Getpid(), Holder(dir))
+
+		_, err = Acquire(context.Background(), dir, 20*time.Millisecond)
+		assert.ErrorIs(t, err, ErrLocked)
+		assert.ErrorContains(t, err, fmt.Sprintf(
+			"another nvimm is running (pid %d)", os.Getpid()))
+		assert.ErrorContains(t, err, filepath.Join(dir, FileName))
+
+		assert.NoError(t, l.Release())
+		assert.NoError(t, l.Release())
+		l, err = Acquire(context.Background(), dir, 0)
+		assert.NoError(t, err)
+		assert.NoError(t, l.Release())
+		assert.FileExists(t, filepath.Join(dir, FileName))
+	})
+
+	t.Run("should wait for the lock to be released", func(t *testing.T) {
+		dir := t.TempDir()
+		l, err := Acquire(context.Background(), dir, 0)
+		assert.NoError(t, err)
+		time.AfterFunc(20*time.Millisecond, func() { l.Release() })
+		other, err := Acquire(context.Background(), dir, time.Minute)
+		assert.NoError(t, err)
+		assert.NoError(t, other.Release())
+	})
+
+	t.Run("should stop waiting when canceled", func(t *testing.T) {
+		dir := t.TempDir()
+		l, err := Acquire(context.Background(), dir, 0)
+		assert.NoError(t, err)
+		defer l.Release()
+		cause := errors.New("interrupted")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		time.AfterFunc(20*time.Millisecond, func() { cancel(cause) })
+		_, err = Acquire(ctx, dir, time.Minute)
+		assert.ErrorIs(t, err, cause)
+
+		_, err = Acquire(context.Background(), filepath.Join(dir, "missing"),
+			0)
+		assert.ErrorContains(t, err, "failed to open lock file")
+	})
+}
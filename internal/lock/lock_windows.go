@@ -0,0 +1,33 @@
+//go:build windows
+
+package lock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset is where the locked byte is. Windows locks keep other processes
+// from reading the locked range, so a byte past the pid is locked instead of
+// the whole file.
+const lockOffset = 1 << 30
+
+// tryLock takes the lock of the file without blocking, returning false if
+// another process holds it.
+func tryLock(file *os.File) (bool, error) {
+	ol := &windows.Overlapped{Offset: lockOffset}
+	err := windows.LockFileEx(windows.Handle(file.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0,
+		1, 0, ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlock(file *os.File) error {
+	ol := &windows.Overlapped{Offset: lockOffset}
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, ol)
+}
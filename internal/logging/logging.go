@@ -0,0 +1,89 @@
+// Package logging provides the leveled structured logger used by nvimm and
+// an HTTP transport tracing requests through it.
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Level maps the command line verbosity to a log level. Warnings are logged
+// by default, -v enables informational messages and -vv debug messages,
+// which include HTTP tracing. Quiet only logs errors.
+func Level(verbosity int, quiet bool) slog.Level {
+	switch {
+	case quiet:
+		return slog.LevelError
+	case verbosity == 1:
+		return slog.LevelInfo
+	case verbosity > 1:
+		return slog.LevelDebug
+	}
+	return slog.LevelWarn
+}
+
+// New creates a logger writing records with level or above to w, as JSON
+// lines if format is FormatJSON or as key=value pairs otherwise.
+func New(w io.Writer, format string, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// Discard returns a logger dropping every record.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard,
+		&slog.HandlerOptions{Level: slog.Level(100)}))
+}
+
+// Transport is an http.RoundTripper logging every request and its outcome
+// at debug level.
+type Transport struct {
+	// Base is the transport performing the requests. If nil,
+	// http.DefaultTransport is used.
+	Base   http.RoundTripper
+	Logger *slog.Logger
+}
+
+// NewTransport wraps base with a Transport logging to logger. A nil logger
+// discards the records.
+func NewTransport(base http.RoundTripper, logger *slog.Logger) *Transport {
+	if logger == nil {
+		logger = Discard()
+	}
+	return &Transport{Base: base, Logger: logger}
+}
+
+// RoundTrip performs the request with the base transport.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	url := req.URL.Redacted()
+	t.Logger.Debug("http request", "method", req.Method, "url", url)
+	start := time.Now()
+	res, err := base.RoundTrip(req)
+	if err != nil {
+		t.Logger.Debug("http request failed", "method", req.Method,
+			"url", url, "duration", time.Since(start), "error", err)
+		return nil, err
+	}
+	attrs := []any{"method", req.Method, "url", url, "status",
+		res.StatusCode, "duration", time.Since(start), "content_length",
+		res.ContentLength}
+	if remaining := res.Header.Get("X-RateLimit-Remaining"); remaining != "" {
+		attrs = append(attrs, "ratelimit_remaining", remaining)
+	}
+	t.Logger.Debug("http response", attrs...)
+	return res, nil
+}
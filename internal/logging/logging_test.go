@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogging(t *testing.T) {
+
+	t.Run("should map verbosity to levels", func(t *testing.T) {
+		assert.Equal(t, slog.LevelWarn, Level(0, false))
+		assert.Equal(t, slog.LevelInfo, Level(1, false))
+		assert.Equal(t, slog.LevelDebug, Level(2, false))
+		assert.Equal(t, slog.LevelDebug, Level(3, false))
+		assert.Equal(t, slog.LevelError, Level(2, true))
+	})
+
+	t.Run("should write json records above the level", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		log := New(buf, FormatJSON, slog.LevelInfo)
+		log.Debug("hidden")
+		log.Info("shown", "release", "0.11.5")
+		record := map[string]any{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		assert.Equal(t, "shown", record["msg"])
+		assert.Equal(t, "0.11.5", record["release"])
+	})
+
+	t.Run("should trace http requests at debug", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-RateLimit-Remaining", "59")
+				w.WriteHeader(http.StatusTeapot)
+			}))
+		defer srv.Close()
+
+		buf := &bytes.Buffer{}
+		client := &http.Client{Transport: NewTransport(nil,
+			New(buf, FormatText, slog.LevelDebug))}
+		res, err := client.Get(srv.URL + "/releases")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Contains(t, buf.String(), "msg=\"http request\" method=GET")
+		assert.Contains(t, buf.String(), "status=418")
+		assert.Contains(t, buf.String(), "ratelimit_remaining=59")
+	})
+}
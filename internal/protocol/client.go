@@ -3,12 +3,26 @@
 package protocol
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	peasant "github.com/candango/gopeasant"
+	"github.com/candango/nvimm/internal/logging"
 )
 
+// GithubApiUrl is the base URL of the GitHub REST API.
+const GithubApiUrl = "https://api.github.com"
+
+// ErrRateLimited is returned when GitHub refuses a request because the API
+// rate limit is exceeded.
+var ErrRateLimited = errors.New("GitHub API rate limit exceeded")
+
 // GithubDirectoryProvider is an in-memory implementation of DirectoryProvider.
 // It points to github.
 type GithubDirectoryProvider struct {
@@ -19,14 +33,28 @@ type GithubDirectoryProvider struct {
 // It constructs the "releases" endpoint using the provider's URL.
 func (p *GithubDirectoryProvider) Directory() (map[string]any, error) {
 	return map[string]any{
-		"releases": p.GetUrl() + "/releases",
+		"releases":   p.GetUrl() + "/releases",
+		"rate_limit": p.apiUrl() + "/rate_limit",
 	}, nil
 }
 
 // GetUrl returns the URL configured for the GitHub provider pointing to the
 // Neovim repository.
 func (p *GithubDirectoryProvider) GetUrl() string {
-	return "https://api.github.com/repos/neovim/neovim"
+	return ReleasesRepoUrl(p.apiUrl())
+}
+
+func (p *GithubDirectoryProvider) apiUrl() string {
+	if p.url == "" {
+		return GithubApiUrl
+	}
+	return p.url
+}
+
+// ReleasesRepoUrl returns the URL of the Neovim repository in the GitHub API
+// at apiUrl.
+func ReleasesRepoUrl(apiUrl string) string {
+	return apiUrl + "/repos/neovim/neovim"
 }
 
 // SetTransport is a no-op for GithubDirectoryProvider, as it does not use a
@@ -39,31 +67,78 @@ func (p *GithubDirectoryProvider) SetTransport(_ peasant.Transport) error {
 // GitHub-specific operations.
 type GithubTransport struct {
 	*peasant.HttpTransport
+	logger *slog.Logger
 }
 
 // NewGithubTransport initializes a new GithubTransport using a
-// GithubDirectoryProvider and a default HTTP transport.
-func NewGithubTransport() (*GithubTransport, error) {
-	p := &GithubDirectoryProvider{}
+// GithubDirectoryProvider for the GitHub API at apiUrl, GithubApiUrl if
+// empty, performing the requests with client. When client is nil, a client
+// without timeouts tracing the requests at debug level through the logger,
+// which may be nil, is used.
+func NewGithubTransport(apiUrl string, client *http.Client,
+	logger *slog.Logger) (*GithubTransport, error) {
+	p := &GithubDirectoryProvider{url: apiUrl}
 	ht, err := peasant.NewHttpTransport(p)
 	if err != nil {
 		return nil, err
 	}
+	if logger == nil {
+		logger = logging.Discard()
+	}
+	if client == nil {
+		client, err = NewClient(ClientOptions{}, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ht.Client = *client
 	return &GithubTransport{
 		ht,
+		logger,
 	}, nil
 }
 
-// GetReleases performs an HTTP GET request to the releases endpoint and
-// returns the raw http.Response. It returns an error if the request fails or
-// if the status code indicates a non-success result (greater than 299).
-func (gt *GithubTransport) GetReleases() (*http.Response, error) {
+// StatusError returns the error for a response with a failure status,
+// wrapping ErrRateLimited when the rate limit was exceeded.
+func StatusError(res *http.Response) error {
+	if (res.StatusCode == http.StatusForbidden ||
+		res.StatusCode == http.StatusTooManyRequests) &&
+		res.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"),
+			10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrRateLimited, res.Status)
+		}
+		return fmt.Errorf("%w, it resets at %s", ErrRateLimited,
+			time.Unix(reset, 0).Format(time.TimeOnly))
+	}
+	return errors.New(res.Status)
+}
+
+// RateLimit is the status of the GitHub core API rate limit, which applies to
+// the releases endpoint.
+type RateLimit struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"`
+}
+
+// ResetAt returns when the rate limit window resets.
+func (rl *RateLimit) ResetAt() time.Time {
+	return time.Unix(rl.Reset, 0)
+}
+
+// GetRateLimit performs an HTTP GET request to the rate limit endpoint, which
+// does not count against the rate limit, and returns the core rate limit.
+func (gt *GithubTransport) GetRateLimit(ctx context.Context) (*RateLimit,
+	error) {
 	d, err := gt.Directory()
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodGet, d["releases"].(string), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		d["rate_limit"].(string), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -72,9 +147,18 @@ func (gt *GithubTransport) GetReleases() (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode > 299 {
-		return nil, errors.New(res.Status)
+		return nil, StatusError(res)
+	}
+	body := struct {
+		Resources struct {
+			Core RateLimit `json:"core"`
+		} `json:"resources"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode rate limit: %w", err)
 	}
-	return res, nil
+	return &body.Resources.Core, nil
 }
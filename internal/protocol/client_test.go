@@ -1,25 +1,43 @@
 package protocol
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
-	"github.com/candango/httpok/testrunner"
+	"github.com/candango/nvimm/internal/githubtest"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGithubTransport(t *testing.T) {
-	gt, err := NewGithubTransport()
+	data, err := os.ReadFile(filepath.Join("..", "cli", "testdata",
+		"releases.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := githubtest.NewServer(t, data)
+	ctx := context.Background()
+	gt, err := NewGithubTransport(srv.URL, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Run("NewNonceUrl should return url if DirectoryKey is valid and error otherwise", func(t *testing.T) {
-		res, err := gt.GetReleases()
-		if err != nil {
-			t.Error(err)
-		}
+	t.Run("should get the rate limit", func(t *testing.T) {
+		rl, err := gt.GetRateLimit(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 60, rl.Limit)
+		assert.Equal(t, 60, rl.Remaining)
+		assert.Equal(t, githubtest.RateLimitReset.Unix(), rl.ResetAt().Unix())
+	})
+
+	t.Run("should default to the GitHub API", func(t *testing.T) {
+		gt, err := NewGithubTransport("", nil, nil)
+		assert.NoError(t, err)
+		d, err := gt.Directory()
 		assert.NoError(t, err)
-		releases := []map[string]any{}
-		testrunner.BodyAsJson(t, res, &releases)
+		assert.Equal(t, GithubApiUrl+"/repos/neovim/neovim/releases",
+			d["releases"])
+		assert.Equal(t, GithubApiUrl+"/rate_limit", d["rate_limit"])
 	})
 }
@@ -0,0 +1,205 @@
+package protocol
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/candango/nvimm/internal/logging"
+)
+
+// ClientOptions configure the HTTP client created by NewClient.
+type ClientOptions struct {
+	// ConnectTimeout limits establishing the connection, including the TLS
+	// handshake. Zero means no limit.
+	ConnectTimeout time.Duration
+	// ReadTimeout limits how long the server may go without sending data,
+	// waiting for the response headers or reading the body. Zero means no
+	// limit. Long downloads are not limited while data keeps arriving.
+	ReadTimeout time.Duration
+	// Proxy is the URL of the proxy every request goes through, credentials
+	// included as user information, except for the hosts listed in
+	// NO_PROXY. When empty, HTTPS_PROXY, HTTP_PROXY and NO_PROXY are used.
+	Proxy string
+	// CABundles are PEM files with certificate authorities trusted in
+	// addition to the system ones.
+	CABundles []string
+	// ClientCert and ClientKey are the PEM files of the certificate
+	// presented to servers requesting one. The key may be in the certificate
+	// file, in which case ClientKey may be empty.
+	ClientCert string
+	ClientKey  string
+	// Insecure disables the verification of server certificates.
+	Insecure bool
+	// Retry tells how requests failing with a transient error, a 5xx or a
+	// 429 status are retried.
+	Retry RetryPolicy
+}
+
+// NewClient creates an HTTP client with the options, logging every request
+// at debug level and every retry at info level through the logger, which may
+// be nil.
+func NewClient(opts ClientOptions, logger *slog.Logger) (*http.Client,
+	error) {
+	dialer := &net.Dialer{
+		Timeout:   opts.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network string,
+		addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil || opts.ReadTimeout <= 0 {
+			return conn, err
+		}
+		return &idleConn{Conn: conn, timeout: opts.ReadTimeout}, nil
+	}
+	transport.TLSHandshakeTimeout = opts.ConnectTimeout
+	transport.ResponseHeaderTimeout = opts.ReadTimeout
+
+	if opts.Proxy != "" {
+		proxyUrl, err := url.Parse(opts.Proxy)
+		if err != nil || proxyUrl.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %s",
+				redactedUrl(opts.Proxy))
+		}
+		noProxy := os.Getenv("NO_PROXY")
+		if noProxy == "" {
+			noProxy = os.Getenv("no_proxy")
+		}
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(noProxy, req.URL.Host) {
+				return nil, nil
+			}
+			return proxyUrl, nil
+		}
+	}
+
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	var rt http.RoundTripper = logging.NewTransport(transport, logger)
+	if opts.Retry.Retries > 0 {
+		rt = NewRetryTransport(rt, opts.Retry, logger)
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// newTLSConfig returns the TLS configuration trusting the CA bundles and
+// presenting the client certificate of the options.
+func newTLSConfig(opts ClientOptions) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: opts.Insecure}
+	if len(opts.CABundles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, bundle := range opts.CABundles {
+			data, err := os.ReadFile(bundle)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("no certificate found in CA bundle %s",
+					bundle)
+			}
+		}
+		config.RootCAs = pool
+	}
+	if opts.ClientCert == "" && opts.ClientKey != "" {
+		return nil, fmt.Errorf("client key %s informed without a client "+
+			"certificate", opts.ClientKey)
+	}
+	if opts.ClientCert != "" {
+		key := opts.ClientKey
+		if key == "" {
+			key = opts.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w",
+				err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// bypassProxy reports whether the host, with an optional port, matches the
+// comma separated NO_PROXY list. Entries are "*", IP addresses, CIDR ranges
+// and domains, matching their subdomains too, with an optional port.
+func bypassProxy(noProxy string, host string) bool {
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname = host
+	}
+	hostname = strings.ToLower(strings.Trim(hostname, "[]"))
+	ip := net.ParseIP(hostname)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+		entry = strings.Trim(entry, "[]")
+		if entryIp := net.ParseIP(entry); entryIp != nil {
+			if ip != nil && entryIp.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if hostname == entry || strings.HasSuffix(hostname, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedUrl returns the url with its password masked, or a placeholder if
+// it cannot be parsed.
+func redactedUrl(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "<unparseable>"
+	}
+	return u.Redacted()
+}
+
+// idleConn is a connection whose reads fail when no data arrives within the
+// timeout.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
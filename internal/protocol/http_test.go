@@ -0,0 +1,172 @@
+package protocol
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writePEM writes the blocks to a file in a temporary directory and returns
+// its path.
+func writePEM(t *testing.T, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	data := []byte{}
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// clientCert returns a self signed client certificate and its key as PEM
+// blocks.
+func clientCert(t *testing.T) (*pem.Block, *pem.Block) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "nvimm"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pem.Block{Type: "CERTIFICATE", Bytes: der},
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}
+}
+
+func TestNewClient(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	})
+
+	t.Run("should go through the proxy with credentials", func(t *testing.T) {
+		t.Setenv("NO_PROXY", "")
+		auth := ""
+		proxy := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Proxy-Authorization")
+				w.Write([]byte(r.URL.String()))
+			}))
+		defer proxy.Close()
+		client, err := NewClient(ClientOptions{
+			Proxy: "http://nvimm:secret@" + proxy.Listener.Addr().String(),
+		}, nil)
+		assert.NoError(t, err)
+		res, err := client.Get("http://github.example/releases")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, "Basic bnZpbW06c2VjcmV0", auth)
+	})
+
+	t.Run("should trust the CA bundles", func(t *testing.T) {
+		srv := httptest.NewTLSServer(ok)
+		defer srv.Close()
+		client, err := NewClient(ClientOptions{}, nil)
+		assert.NoError(t, err)
+		_, err = client.Get(srv.URL)
+		var unknown x509.UnknownAuthorityError
+		assert.ErrorAs(t, err, &unknown)
+
+		bundle := writePEM(t, "ca.pem", &pem.Block{Type: "CERTIFICATE",
+			Bytes: srv.Certificate().Raw})
+		client, err = NewClient(ClientOptions{CABundles: []string{bundle}},
+			nil)
+		assert.NoError(t, err)
+		res, err := client.Get(srv.URL)
+		assert.NoError(t, err)
+		res.Body.Close()
+
+		client, err = NewClient(ClientOptions{Insecure: true}, nil)
+		assert.NoError(t, err)
+		res, err = client.Get(srv.URL)
+		assert.NoError(t, err)
+		res.Body.Close()
+	})
+
+	t.Run("should present the client certificate", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(ok)
+		srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+		srv.StartTLS()
+		defer srv.Close()
+		bundle := writePEM(t, "ca.pem", &pem.Block{Type: "CERTIFICATE",
+			Bytes: srv.Certificate().Raw})
+		cert, key := clientCert(t)
+
+		client, err := NewClient(ClientOptions{
+			CABundles:  []string{bundle},
+			ClientCert: writePEM(t, "client.pem", cert, key),
+		}, nil)
+		assert.NoError(t, err)
+		res, err := client.Get(srv.URL)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		body := make([]byte, 5)
+		res.Body.Read(body)
+		assert.Equal(t, "nvimm", string(body))
+	})
+
+	t.Run("should report invalid settings", func(t *testing.T) {
+		_, err := NewClient(ClientOptions{Proxy: "http://user:pass@"}, nil)
+		assert.EqualError(t, err, "invalid proxy url http://user:xxxxx@")
+		_, err = NewClient(ClientOptions{CABundles: []string{"missing.pem"}},
+			nil)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		empty := writePEM(t, "empty.pem")
+		_, err = NewClient(ClientOptions{CABundles: []string{empty}}, nil)
+		assert.ErrorContains(t, err, "no certificate found in CA bundle")
+		_, err = NewClient(ClientOptions{ClientKey: "key.pem"}, nil)
+		assert.ErrorContains(t, err, "without a client certificate")
+	})
+
+	t.Run("should bypass the proxy for NO_PROXY hosts", func(t *testing.T) {
+		noProxy := "localhost, .internal.example,github.example:8443," +
+			"10.0.0.0/8,::1"
+		for host, bypass := range map[string]bool{
+			"localhost":            true,
+			"localhost:8080":       true,
+			"api.internal.example": true,
+			"internal.example":     true,
+			"github.example:8443":  true,
+			"github.example":       false,
+			"10.1.2.3:443":         true,
+			"[::1]:443":            true,
+			"api.github.com":       false,
+			"notinternal.example":  false,
+			"192.168.0.1":          false,
+		} {
+			assert.Equal(t, bypass, bypassProxy(noProxy, host), host)
+		}
+		assert.True(t, bypassProxy("*", "api.github.com"))
+		assert.False(t, bypassProxy("", "api.github.com"))
+	})
+}
@@ -0,0 +1,190 @@
+package protocol
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/candango/nvimm/internal/logging"
+)
+
+// DefaultBaseBackoff is the wait before the first retry when the policy sets
+// none, doubled on every following retry.
+const DefaultBaseBackoff = 500 * time.Millisecond
+
+// RetryPolicy tells how failed requests are retried. The zero value does not
+// retry.
+type RetryPolicy struct {
+	// Retries is how many times a failed request is retried.
+	Retries int
+	// BaseBackoff is the wait before the first retry, DefaultBaseBackoff if
+	// zero. It doubles on every retry.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the wait between retries. A Retry-After longer than it
+	// is not waited for, the response is returned instead. Zero means no
+	// cap.
+	MaxBackoff time.Duration
+}
+
+// Backoff returns the wait before the retry, counted from 1.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	wait := p.BaseBackoff
+	if wait <= 0 {
+		wait = DefaultBaseBackoff
+	}
+	for i := 1; i < retry; i++ {
+		wait *= 2
+		if p.MaxBackoff > 0 && wait >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return wait
+}
+
+// Wait sleeps for d, returning the context error if it is done first.
+func Wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Transient reports whether the error is a network failure worth retrying,
+// like a reset connection, a connection closed before the end of the body or
+// a timeout. Canceled contexts are not transient.
+func Transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// RetryTransport is an http.RoundTripper retrying GET requests failing with
+// a transient error, a 5xx status or a 429 status, waiting the Retry-After
+// of the response when informed or an exponential backoff otherwise. Every
+// retry is logged at info level.
+type RetryTransport struct {
+	// Base is the transport performing the requests. If nil,
+	// http.DefaultTransport is used.
+	Base   http.RoundTripper
+	Policy RetryPolicy
+	Logger *slog.Logger
+	// wait sleeps between attempts, Wait if nil.
+	wait func(ctx context.Context, d time.Duration) error
+	// now returns the current time to interpret Retry-After dates, time.Now
+	// if nil.
+	now func() time.Time
+}
+
+// NewRetryTransport wraps base with a RetryTransport following the policy
+// and logging to logger. A nil logger discards the records.
+func NewRetryTransport(base http.RoundTripper, policy RetryPolicy,
+	logger *slog.Logger) *RetryTransport {
+	if logger == nil {
+		logger = logging.Discard()
+	}
+	return &RetryTransport{Base: base, Policy: policy, Logger: logger}
+}
+
+// RoundTrip performs the request with the base transport, retrying it as
+// the policy allows.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response,
+	error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Method != http.MethodGet || req.Body != nil &&
+		req.Body != http.NoBody {
+		return base.RoundTrip(req)
+	}
+	wait := t.wait
+	if wait == nil {
+		wait = Wait
+	}
+	for retry := 1; ; retry++ {
+		res, err := base.RoundTrip(req)
+		if retry > t.Policy.Retries {
+			return res, err
+		}
+		backoff := t.Policy.Backoff(retry)
+		reason := ""
+		switch {
+		case err != nil:
+			if !Transient(err) || req.Context().Err() != nil {
+				return res, err
+			}
+			reason = err.Error()
+		case res.StatusCode >= 500 ||
+			res.StatusCode == http.StatusTooManyRequests:
+			reason = res.Status
+			if after, ok := t.retryAfter(res); ok {
+				if t.Policy.MaxBackoff > 0 && after > t.Policy.MaxBackoff {
+					t.Logger.Info("not retrying, the server asks to wait "+
+						"longer than the maximum backoff", "url",
+						req.URL.Redacted(), "status", res.StatusCode,
+						"retry_after", after)
+					return res, nil
+				}
+				backoff = after
+			}
+			io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+			res.Body.Close()
+		default:
+			return res, nil
+		}
+		t.Logger.Info("retrying request", "url", req.URL.Redacted(),
+			"retry", retry, "of", t.Policy.Retries, "reason", reason,
+			"backoff", backoff)
+		if err := wait(req.Context(), backoff); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryAfter returns the wait asked by the Retry-After header of the
+// response, given in seconds or as an HTTP date.
+func (t *RetryTransport) retryAfter(res *http.Response) (time.Duration,
+	bool) {
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+	if after := date.Sub(now()); after > 0 {
+		return after, true
+	}
+	return 0, true
+}
@@ -0,0 +1,158 @@
+package protocol
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTransport(t *testing.T) {
+	// serve answers the statuses in order, with the Retry-After headers at
+	// the same positions, then 200 OK.
+	serve := func(statuses []int, retryAfter []string) (*httptest.Server,
+		*int) {
+		hits := 0
+		srv := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				if hits > len(statuses) {
+					w.Write([]byte("ok"))
+					return
+				}
+				if hits <= len(retryAfter) && retryAfter[hits-1] != "" {
+					w.Header().Set("Retry-After", retryAfter[hits-1])
+				}
+				w.WriteHeader(statuses[hits-1])
+			}))
+		t.Cleanup(srv.Close)
+		return srv, &hits
+	}
+	newTransport := func(policy RetryPolicy) (*RetryTransport,
+		*[]time.Duration) {
+		waits := []time.Duration{}
+		rt := NewRetryTransport(nil, policy, nil)
+		rt.wait = func(_ context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		}
+		rt.now = func() time.Time {
+			return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+		}
+		return rt, &waits
+	}
+	policy := RetryPolicy{Retries: 3, BaseBackoff: time.Second,
+		MaxBackoff: 3 * time.Second}
+
+	t.Run("should retry server errors with backoff", func(t *testing.T) {
+		srv, hits := serve([]int{500, 502, 503}, nil)
+		rt, waits := newTransport(policy)
+		res, err := (&http.Client{Transport: rt}).Get(srv.URL)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 4, *hits)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second,
+			3 * time.Second}, *waits)
+	})
+
+	t.Run("should return the last failure", func(t *testing.T) {
+		srv, hits := serve([]int{503, 503, 503, 503, 503}, nil)
+		rt, _ := newTransport(policy)
+		res, err := (&http.Client{Transport: rt}).Get(srv.URL)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+		assert.Equal(t, 4, *hits)
+	})
+
+	t.Run("should wait the Retry-After of 429", func(t *testing.T) {
+		srv, hits := serve([]int{429, 429}, []string{"2",
+			"Sun, 18 Oct 2026 12:00:01 GMT"})
+		rt, waits := newTransport(policy)
+		res, err := (&http.Client{Transport: rt}).Get(srv.URL)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 3, *hits)
+		assert.Equal(t, []time.Duration{2 * time.Second, time.Second},
+			*waits)
+
+		srv, hits = serve([]int{429}, []string{"60"})
+		rt, waits = newTransport(policy)
+		res, err = (&http.Client{Transport: rt}).Get(srv.URL)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+		assert.Equal(t, 1, *hits)
+		assert.Empty(t, *waits)
+	})
+
+	t.Run("should not retry other failures", func(t *testing.T) {
+		srv, hits := serve([]int{404}, nil)
+		rt, _ := newTransport(policy)
+		res, err := (&http.Client{Transport: rt}).Get(srv.URL)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, 1, *hits)
+
+		srv, hits = serve([]int{503}, nil)
+		res, err = (&http.Client{Transport: rt}).Post(srv.URL, "text/plain",
+			strings.NewReader("body"))
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, 1, *hits)
+	})
+
+	t.Run("should stop waiting when canceled", func(t *testing.T) {
+		srv, hits := serve([]int{503}, nil)
+		rt := NewRetryTransport(nil, RetryPolicy{Retries: 3,
+			BaseBackoff: time.Hour}, nil)
+		ctx, cancel := context.WithTimeout(context.Background(),
+			10*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL,
+			nil)
+		assert.NoError(t, err)
+		_, err = (&http.Client{Transport: rt}).Do(req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, *hits)
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+
+	t.Run("should double the backoff up to the maximum", func(t *testing.T) {
+		policy := RetryPolicy{Retries: 10, MaxBackoff: 3 * time.Second}
+		assert.Equal(t, DefaultBaseBackoff, policy.Backoff(1))
+		assert.Equal(t, time.Second, policy.Backoff(2))
+		assert.Equal(t, 2*time.Second, policy.Backoff(3))
+		assert.Equal(t, 3*time.Second, policy.Backoff(4))
+		assert.Equal(t, 3*time.Second, policy.Backoff(10))
+	})
+
+	t.Run("should tell transient errors", func(t *testing.T) {
+		assert.True(t, Transient(io.ErrUnexpectedEOF))
+		assert.True(t, Transient(&timeoutError{}))
+		assert.True(t, Transient(errors.Join(errors.New("read"),
+			syscall.ECONNRESET)))
+		assert.False(t, Transient(nil))
+		assert.False(t, Transient(context.Canceled))
+		assert.False(t, Transient(errors.New("404 Not Found")))
+	})
+}
+
+// timeoutError is a network timeout error.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
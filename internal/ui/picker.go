@@ -0,0 +1,287 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// ErrCanceled is returned by Pick when the user cancels the selection.
+var ErrCanceled = errors.New("selection canceled")
+
+// pickerHeight is the maximum number of items shown at once by the
+// interactive picker.
+const pickerHeight = 10
+
+const (
+	hideCursor = "\033[?25l"
+	showCursor = "\033[?25h"
+)
+
+// Item is a choice offered by Pick. The search matches both the label and the
+// detail, which is shown dimmed after the label.
+type Item struct {
+	Label  string
+	Detail string
+}
+
+func (i Item) text() string {
+	if i.Detail == "" {
+		return i.Label
+	}
+	return i.Label + " " + i.Detail
+}
+
+// Pick asks the user to choose one of the items and returns its index. When
+// both the UI and in are terminals, an interactive picker filtered as the
+// user types is shown. Otherwise the items are listed with numbers and the
+// number, or the label, of the chosen item is read from in.
+func (u *UI) Pick(in io.Reader, prompt string, items []Item) (int, error) {
+	if len(items) == 0 {
+		return -1, fmt.Errorf("nothing to pick from")
+	}
+	if !u.TTY || !IsTerminal(in) {
+		return u.pickNumbered(in, prompt, items)
+	}
+	f := in.(*os.File)
+	state, err := term.MakeRaw(int(f.Fd()))
+	if err != nil {
+		return u.pickNumbered(in, prompt, items)
+	}
+	defer term.Restore(int(f.Fd()), state)
+	return u.pickInteractive(in, prompt, items)
+}
+
+// pickNumbered implements Pick when not on a terminal.
+func (u *UI) pickNumbered(in io.Reader, prompt string,
+	items []Item) (int, error) {
+	u.Printf("%s\n", prompt)
+	for i, item := range items {
+		u.Printf("%3d) %s\n", i+1, item.text())
+	}
+	u.Printf("Enter a number [1-%d]: ", len(items))
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if !IsTerminal(in) {
+		// The answer was not echoed, end the prompt line.
+		u.Printf("\n")
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		if err != nil && !errors.Is(err, io.EOF) {
+			return -1, err
+		}
+		return -1, ErrCanceled
+	}
+	if n, err := strconv.Atoi(line); err == nil {
+		if n < 1 || n > len(items) {
+			return -1, fmt.Errorf("invalid selection %d, expected a number "+
+				"from 1 to %d", n, len(items))
+		}
+		return n - 1, nil
+	}
+	for i, item := range items {
+		if item.Label == line {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("invalid selection %q", line)
+}
+
+const (
+	keyNone rune = -(iota + 1)
+	keyUp
+	keyDown
+	keyEnter
+	keyBackspace
+	keyClear
+	keyCancel
+)
+
+// readKey reads a key press from a terminal in raw mode, translating the
+// control characters and escape sequences used by the picker.
+func readKey(r *bufio.Reader) (rune, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return keyNone, err
+	}
+	switch c {
+	case '\r', '\n':
+		return keyEnter, nil
+	case 127, '\b':
+		return keyBackspace, nil
+	case 3, 4: // Ctrl-C, Ctrl-D
+		return keyCancel, nil
+	case 14: // Ctrl-N
+		return keyDown, nil
+	case 16: // Ctrl-P
+		return keyUp, nil
+	case 21: // Ctrl-U
+		return keyClear, nil
+	case 27:
+		next, _, err := r.ReadRune()
+		if err != nil {
+			return keyCancel, nil
+		}
+		if next != '[' && next != 'O' {
+			return keyCancel, nil
+		}
+		code, _, err := r.ReadRune()
+		if err != nil {
+			return keyNone, err
+		}
+		switch code {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		}
+		return keyNone, nil
+	}
+	if !unicode.IsPrint(c) {
+		return keyNone, nil
+	}
+	return c, nil
+}
+
+// pickInteractive implements Pick on terminals. It expects in to be in raw
+// mode, so lines are ended with "\r\n".
+func (u *UI) pickInteractive(in io.Reader, prompt string,
+	items []Item) (int, error) {
+	r := bufio.NewReader(in)
+	query := []rune{}
+	matches := filterItems("", items)
+	cursor := 0
+	drawn := 0
+	u.Printf(hideCursor)
+	defer u.Printf(showCursor)
+	for {
+		drawn = u.drawPicker(drawn, prompt, string(query), items, matches,
+			cursor)
+		key, err := readKey(r)
+		if err != nil {
+			key = keyCancel
+		}
+		switch key {
+		case keyNone:
+		case keyCancel:
+			u.clearPicker(drawn)
+			return -1, ErrCanceled
+		case keyEnter:
+			if len(matches) == 0 {
+				continue
+			}
+			u.clearPicker(drawn)
+			u.Printf("%s %s\r\n", prompt, items[matches[cursor]].Label)
+			return matches[cursor], nil
+		case keyUp:
+			if cursor > 0 {
+				cursor--
+			}
+		case keyDown:
+			if cursor < len(matches)-1 {
+				cursor++
+			}
+		case keyBackspace, keyClear:
+			if len(query) == 0 {
+				continue
+			}
+			query = query[:len(query)-1]
+			if key == keyClear {
+				query = query[:0]
+			}
+			matches, cursor = filterItems(string(query), items), 0
+		default:
+			query = append(query, key)
+			matches, cursor = filterItems(string(query), items), 0
+		}
+	}
+}
+
+// drawPicker replaces the previously drawn lines with the prompt and the
+// window of matches around the cursor. It returns the number of lines drawn.
+func (u *UI) drawPicker(drawn int, prompt string, query string,
+	items []Item, matches []int, cursor int) int {
+	u.clearPicker(drawn)
+	u.Printf("%s %s  %s", prompt, query,
+		u.Dim(fmt.Sprintf("%d/%d", len(matches), len(items))))
+	offset := max(0, cursor-pickerHeight+1)
+	lines := 1
+	for i := offset; i < len(matches) && i < offset+pickerHeight; i++ {
+		item := items[matches[i]]
+		detail := ""
+		if item.Detail != "" {
+			detail = " " + u.Dim(item.Detail)
+		}
+		if i == cursor {
+			u.Printf("\r\n%s%s", u.Green("> "+item.Label), detail)
+		} else {
+			u.Printf("\r\n  %s%s", item.Label, detail)
+		}
+		lines++
+	}
+	return lines
+}
+
+// clearPicker moves the cursor back to the first drawn line and clears the
+// screen below it.
+func (u *UI) clearPicker(drawn int) {
+	if drawn > 1 {
+		u.Printf("\033[%dA", drawn-1)
+	}
+	if drawn > 0 {
+		u.Printf("\r\033[J")
+	}
+}
+
+// filterItems returns the indexes of the items fuzzy matching the query,
+// the best matches first. Items with the same score keep their order.
+func filterItems(query string, items []Item) []int {
+	matches := []int{}
+	scores := map[int]int{}
+	for i, item := range items {
+		score, ok := fuzzyScore(query, item.text())
+		if !ok {
+			continue
+		}
+		matches = append(matches, i)
+		scores[i] = score
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		return scores[matches[a]] > scores[matches[b]]
+	})
+	return matches
+}
+
+// fuzzyScore checks if the runes of query appear in s in the same order,
+// ignoring case. Consecutive runes and a match at the start of s score
+// higher.
+func fuzzyScore(query string, s string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	score, qi, prev := 0, 0, -2
+	for i, r := range []rune(strings.ToLower(s)) {
+		if qi == len(q) {
+			break
+		}
+		if r != q[qi] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 2
+		}
+		if i == 0 {
+			score += 3
+		}
+		prev = i
+		qi++
+	}
+	return score, qi == len(q)
+}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTerminal replays key presses to the picker and records what it draws.
+type fakeTerminal struct {
+	keys *strings.Reader
+	out  *bytes.Buffer
+	ui   *UI
+}
+
+func newFakeTerminal(keys string) *fakeTerminal {
+	out := &bytes.Buffer{}
+	return &fakeTerminal{
+		keys: strings.NewReader(keys),
+		out:  out,
+		ui:   &UI{Out: out, TTY: true},
+	}
+}
+
+func (ft *fakeTerminal) pick(items []Item) (int, error) {
+	return ft.ui.pickInteractive(ft.keys, "Select a release:", items)
+}
+
+func TestPicker(t *testing.T) {
+	items := []Item{
+		{Label: "nightly", Detail: "2026-10-17 g8b2e9b2a1"},
+		{Label: "0.11.5", Detail: "current, stable"},
+		{Label: "0.10.4"},
+		{Label: "0.10.3"},
+	}
+
+	t.Run("should select with arrow keys", func(t *testing.T) {
+		ft := newFakeTerminal("\033[B\033[B\033[B\033[A\r")
+		i, err := ft.pick(items)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, i)
+		assert.Contains(t, ft.out.String(), "> 0.10.4")
+		assert.True(t, strings.HasSuffix(ft.out.String(),
+			"Select a release: 0.10.4\r\n"+showCursor))
+	})
+
+	t.Run("should filter while typing", func(t *testing.T) {
+		ft := newFakeTerminal("0103\r")
+		i, err := ft.pick(items)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, i)
+
+		ft = newFakeTerminal("stab\r")
+		i, err = ft.pick(items)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, i)
+		assert.Contains(t, ft.out.String(), "Select a release: stab  1/4")
+	})
+
+	t.Run("should edit the query", func(t *testing.T) {
+		ft := newFakeTerminal("xyz\x7f\x7f\x7fnig\r")
+		i, err := ft.pick(items)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, i)
+	})
+
+	t.Run("should ignore enter without matches", func(t *testing.T) {
+		ft := newFakeTerminal("xyz\r\x15\r")
+		i, err := ft.pick(items)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, i)
+	})
+
+	t.Run("should cancel", func(t *testing.T) {
+		for _, keys := range []string{"\x03", "\033\033", ""} {
+			ft := newFakeTerminal(keys)
+			_, err := ft.pick(items)
+			assert.ErrorIs(t, err, ErrCanceled)
+		}
+	})
+
+	t.Run("should fall back to a numbered prompt", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		u := &UI{Out: out}
+		i, err := u.Pick(strings.NewReader("3\n"), "Select a release:", items)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, i)
+		assert.Equal(t, "Select a release:\n"+
+			"  1) nightly 2026-10-17 g8b2e9b2a1\n"+
+			"  2) 0.11.5 current, stable\n"+
+			"  3) 0.10.4\n"+
+			"  4) 0.10.3\n"+
+			"Enter a number [1-4]: \n", out.String())
+
+		i, err = u.Pick(strings.NewReader("0.10.3\n"), "Select:", items)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, i)
+
+		_, err = u.Pick(strings.NewReader("5\n"), "Select:", items)
+		assert.Error(t, err)
+
+		_, err = u.Pick(strings.NewReader(""), "Select:", items)
+		assert.ErrorIs(t, err, ErrCanceled)
+	})
+}
@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const frameInterval = 100 * time.Millisecond
+
+var spinnerChars = []rune{'⠋', '⠙', '⠹', '⠸', '⠼', '⠴', '⠦', '⠧', '⠇', '⠏'}
+
+// clearLine returns the cursor to the start of the line and erases it.
+const clearLine = "\r\033[K"
+
+// Spinner shows that a task is running. On terminals it animates the message
+// until stopped, otherwise the message is printed once as a plain line.
+type Spinner struct {
+	ui      *UI
+	msg     string
+	stop    chan struct{}
+	done    chan struct{}
+	started bool
+	once    sync.Once
+}
+
+// NewSpinner creates a spinner for the task described by msg.
+func (u *UI) NewSpinner(msg string) *Spinner {
+	return &Spinner{
+		ui:   u,
+		msg:  msg,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Start starts the animation, or prints the message when not on a terminal.
+func (s *Spinner) Start() {
+	s.started = true
+	if !s.ui.TTY {
+		close(s.done)
+		s.ui.Printf("%s\n", s.msg)
+		return
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(frameInterval)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			s.ui.Printf("\r%s %c", s.msg, spinnerChars[i%len(spinnerChars)])
+			select {
+			case <-s.stop:
+				s.ui.Printf(clearLine)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the animation, waiting for the last frame to be cleared, and
+// reports the outcome of the task with the final message. Calling Stop more
+// than once has no effect.
+func (s *Spinner) Stop(outcome string, finalMsg string) {
+	s.once.Do(func() {
+		close(s.stop)
+		if s.started {
+			<-s.done
+		}
+		s.ui.Report(outcome, finalMsg)
+	})
+}
+
+// Progress reports the progress of a transfer of a known size. It is an
+// io.Writer counting the bytes written, on terminals a progress line is
+// redrawn as the bytes arrive, otherwise only the start and the end of the
+// transfer are printed.
+type Progress struct {
+	ui    *UI
+	msg   string
+	total int64
+	n     int64
+	drawn time.Time
+}
+
+// NewProgress creates a Progress for the transfer described by msg. A total
+// lower than one means the size is unknown. The message is printed right
+// away when not on a terminal.
+func (u *UI) NewProgress(msg string, total int64) *Progress {
+	p := &Progress{ui: u, msg: msg, total: total}
+	if !u.TTY {
+		u.Printf("%s\n", msg)
+	}
+	return p
+}
+
+// Write counts the bytes of b as transferred.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.n += int64(len(b))
+	if p.ui.TTY && time.Since(p.drawn) >= frameInterval {
+		p.draw()
+		p.drawn = time.Now()
+	}
+	return len(b), nil
+}
+
+func (p *Progress) draw() {
+	if p.total < 1 {
+		p.ui.Printf("\r%s %s", p.msg, HumanSize(p.n))
+		return
+	}
+	p.ui.Printf("\r%s %s / %s (%d%%)", p.msg, HumanSize(p.n),
+		HumanSize(p.total), p.n*100/p.total)
+}
+
+// Done clears the progress line and reports the outcome of the transfer with
+// the final message.
+func (p *Progress) Done(outcome string, finalMsg string) {
+	p.Clear()
+	p.ui.Report(outcome, finalMsg)
+}
+
+// Clear clears the progress line, leaving the outcome to be reported by the
+// caller.
+func (p *Progress) Clear() {
+	if p.ui.TTY {
+		p.ui.Printf(clearLine)
+	}
+}
+
+// HumanSize formats a size in bytes using binary units, such as "10.5 MiB".
+func HumanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div),
+		"KMGTPE"[exp])
+}
@@ -0,0 +1,114 @@
+// Package ui renders progress and status messages adapting them to the
+// terminal. Spinners, progress bars and colors are only used when the output
+// is an interactive terminal, otherwise plain lines are written so logs
+// captured in CI stay readable.
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	reset  = "\033[0m"
+	bold   = "\033[1m"
+	dim    = "\033[2m"
+	red    = "\033[31m"
+	green  = "\033[32m"
+	yellow = "\033[33m"
+)
+
+// Outcomes of the tasks reported by Report, Spinner and Progress.
+const (
+	OutcomeOK   = "ok"
+	OutcomeWarn = "warn"
+	OutcomeFail = "fail"
+	OutcomeSkip = "skip"
+)
+
+// UI writes status messages to Out. TTY enables the animated spinners and
+// progress bars and Color enables ANSI colors.
+type UI struct {
+	Out   io.Writer
+	TTY   bool
+	Color bool
+}
+
+// New creates a UI writing to w. Colors are enabled on terminals unless
+// noColor is set, the NO_COLOR environment variable is not empty or TERM is
+// "dumb".
+func New(w io.Writer, noColor bool) *UI {
+	tty := IsTerminal(w)
+	return &UI{Out: w, TTY: tty, Color: colorEnabled(tty, noColor)}
+}
+
+func colorEnabled(tty bool, noColor bool) bool {
+	return tty && !noColor && os.Getenv("NO_COLOR") == "" &&
+		os.Getenv("TERM") != "dumb"
+}
+
+// IsTerminal returns true if v is a file connected to a terminal.
+func IsTerminal(v any) bool {
+	f, ok := v.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// Printf writes a formatted message.
+func (u *UI) Printf(format string, a ...any) {
+	fmt.Fprintf(u.Out, format, a...)
+}
+
+func (u *UI) style(code string, s string) string {
+	if !u.Color {
+		return s
+	}
+	return code + s + reset
+}
+
+// Bold returns s in bold when colors are enabled.
+func (u *UI) Bold(s string) string {
+	return u.style(bold, s)
+}
+
+// Dim returns s dimmed when colors are enabled.
+func (u *UI) Dim(s string) string {
+	return u.style(dim, s)
+}
+
+// Green returns s in green when colors are enabled.
+func (u *UI) Green(s string) string {
+	return u.style(green, s)
+}
+
+// Yellow returns s in yellow when colors are enabled.
+func (u *UI) Yellow(s string) string {
+	return u.style(yellow, s)
+}
+
+// Red returns s in red when colors are enabled.
+func (u *UI) Red(s string) string {
+	return u.style(red, s)
+}
+
+// Report prints msg followed by the outcome of the task it describes, like
+// "Download completed. [OK]".
+func (u *UI) Report(outcome string, msg string) {
+	var marker string
+	switch outcome {
+	case OutcomeOK:
+		marker = u.Green("[OK]")
+	case OutcomeWarn:
+		marker = u.Yellow("[WARN]")
+	case OutcomeFail:
+		marker = u.Red("[FAIL]")
+	case OutcomeSkip:
+		marker = u.Dim("[SKIP]")
+	default:
+		marker = "[" + strings.ToUpper(outcome) + "]"
+	}
+	u.Printf("%s %s\n", msg, marker)
+}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUI(t *testing.T) {
+
+	t.Run("should not use terminal features on plain writers", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		u := New(buf, false)
+		assert.False(t, u.TTY)
+		assert.False(t, u.Color)
+		assert.Equal(t, "done", u.Green("done"))
+	})
+
+	t.Run("should print plain lines when not on a terminal", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		u := &UI{Out: buf}
+		spinner := u.NewSpinner("Extracting archive...")
+		spinner.Start()
+		spinner.Stop(OutcomeFail, "Extraction failed.")
+		spinner.Stop(OutcomeOK, "Extraction completed.")
+		progress := u.NewProgress("Downloading...", 2048)
+		progress.Write(make([]byte, 2048))
+		progress.Done(OutcomeOK, "Download completed.")
+		assert.Equal(t, "Extracting archive...\n"+
+			"Extraction failed. [FAIL]\n"+
+			"Downloading...\n"+
+			"Download completed. [OK]\n", buf.String())
+	})
+
+	t.Run("should animate on a terminal", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		u := &UI{Out: buf, TTY: true, Color: true}
+		spinner := u.NewSpinner("Copying files...")
+		spinner.Start()
+		spinner.Stop(OutcomeOK, "Installation completed.")
+		out := buf.String()
+		assert.True(t, strings.HasPrefix(out, "\rCopying files... ⠋"))
+		assert.True(t, strings.HasSuffix(out, clearLine+
+			"Installation completed. "+green+"[OK]"+reset+"\n"))
+
+		buf.Reset()
+		progress := u.NewProgress("Downloading...", 2048)
+		progress.Write(make([]byte, 1024))
+		assert.Equal(t, "\rDownloading... 1.0 KiB / 2.0 KiB (50%)", buf.String())
+	})
+
+	t.Run("should report each outcome", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		u := &UI{Out: buf, Color: true}
+		u.Report(OutcomeOK, "Checksum verified.")
+		u.Report(OutcomeWarn, "No checksum published.")
+		u.Report(OutcomeFail, "Extraction failed.")
+		u.Report(OutcomeSkip, "Current not changed.")
+		assert.Equal(t, "Checksum verified. "+green+"[OK]"+reset+"\n"+
+			"No checksum published. "+yellow+"[WARN]"+reset+"\n"+
+			"Extraction failed. "+red+"[FAIL]"+reset+"\n"+
+			"Current not changed. "+dim+"[SKIP]"+reset+"\n", buf.String())
+	})
+
+	t.Run("should honor NO_COLOR", func(t *testing.T) {
+		t.Setenv("TERM", "xterm-256color")
+		t.Setenv("NO_COLOR", "")
+		assert.True(t, colorEnabled(true, false))
+		assert.False(t, colorEnabled(true, true))
+		assert.False(t, colorEnabled(false, false))
+		t.Setenv("NO_COLOR", "1")
+		assert.False(t, colorEnabled(true, false))
+	})
+
+	t.Run("should format sizes with binary units", func(t *testing.T) {
+		assert.Equal(t, "512 B", HumanSize(512))
+		assert.Equal(t, "10.5 MiB", HumanSize(11010048))
+	})
+}
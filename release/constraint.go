@@ -0,0 +1,357 @@
+package release
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AliasLatest resolves to the newest release that is not a prerelease.
+	AliasLatest = "latest"
+	// AliasLTS resolves to the newest release of the minor line preceding
+	// the stable one, which is the line still receiving backports.
+	AliasLTS = "lts"
+	// AliasOldStable is a synonym of AliasLTS.
+	AliasOldStable = "oldstable"
+)
+
+var partialRe = regexp.MustCompile(
+	`^v?(0|[1-9]\d*)(?:\.(0|[1-9]\d*))?(?:\.(0|[1-9]\d*))?` +
+		`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+var comparatorRe = regexp.MustCompile(`^(>=|<=|!=|>|<|=|\^|~)?\s*(\S+)$`)
+
+// comparator matches versions against a single operator and version.
+type comparator struct {
+	op string
+	v  Version
+}
+
+func (c comparator) match(v Version) bool {
+	r := v.Compare(c.v)
+	switch c.op {
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	case "!=":
+		return r != 0
+	}
+	return r == 0
+}
+
+func (c comparator) String() string {
+	return c.op + c.v.String()
+}
+
+// Constraint describes which versions are acceptable for a release query.
+// It is built by ParseConstraint from expressions such as "0.10", "^0.10.0",
+// "~0.10.1" or ">=0.9,<0.11".
+type Constraint struct {
+	raw             string
+	comparators     []comparator
+	channel         string
+	allowPrerelease bool
+}
+
+// ParseConstraint parses a constraint expression. Comparators are separated
+// by commas or spaces and must all match. Partial versions are expanded to
+// the whole range they represent, so "0.10" matches any 0.10.x release and
+// "<=0.10" includes 0.10.x as well. Prereleases only match when the
+// expression mentions a prerelease explicitly.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == ChannelNightly {
+		c.channel = ChannelNightly
+		c.allowPrerelease = true
+		return c, nil
+	}
+	terms := strings.FieldsFunc(c.raw, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(terms) == 0 {
+		return c, fmt.Errorf("empty version constraint")
+	}
+	// Allow a space between the operator and the version, like ">= 0.9".
+	for i := 0; i < len(terms); i++ {
+		if strings.Trim(terms[i], "<>=!^~") == "" && i+1 < len(terms) {
+			terms[i] += terms[i+1]
+			terms = append(terms[:i+1], terms[i+2:]...)
+		}
+	}
+	for _, term := range terms {
+		cs, err := parseTerm(term)
+		if err != nil {
+			return c, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		for _, cmp := range cs {
+			if cmp.v.Prerelease != "" {
+				c.allowPrerelease = true
+			}
+		}
+		c.comparators = append(c.comparators, cs...)
+	}
+	return c, nil
+}
+
+// parseTerm converts a single constraint term into one or two comparators.
+func parseTerm(term string) ([]comparator, error) {
+	m := comparatorRe.FindStringSubmatch(term)
+	if m == nil {
+		return nil, fmt.Errorf("invalid term %q", term)
+	}
+	op := m[1]
+	low, high, precision, err := parsePartial(m[2])
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "", "=":
+		if precision == 3 {
+			return []comparator{{"=", low}}, nil
+		}
+		return []comparator{{">=", low}, {"<", high}}, nil
+	case "!=":
+		if precision == 3 {
+			return []comparator{{"!=", low}}, nil
+		}
+		return nil, fmt.Errorf("operator != requires a full version")
+	case ">":
+		if precision == 3 {
+			return []comparator{{">", low}}, nil
+		}
+		return []comparator{{">=", high}}, nil
+	case ">=":
+		return []comparator{{">=", low}}, nil
+	case "<":
+		return []comparator{{"<", low}}, nil
+	case "<=":
+		if precision == 3 {
+			return []comparator{{"<=", low}}, nil
+		}
+		return []comparator{{"<", high}}, nil
+	case "^":
+		upper := Version{Major: low.Major + 1}
+		if low.Major == 0 {
+			upper = Version{Minor: low.Minor + 1}
+			if low.Minor == 0 && precision == 3 {
+				upper = Version{Patch: low.Patch + 1}
+			}
+		}
+		return []comparator{{">=", low}, {"<", upper}}, nil
+	case "~":
+		upper := Version{Major: low.Major, Minor: low.Minor + 1}
+		if precision == 1 {
+			upper = Version{Major: low.Major + 1}
+		}
+		return []comparator{{">=", low}, {"<", upper}}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+// parsePartial parses a possibly partial version returning the lowest
+// version it represents, the first version after its range and how many
+// numeric components were informed.
+func parsePartial(s string) (Version, Version, int, error) {
+	m := partialRe.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, Version{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+	low := Version{Prerelease: m[4]}
+	low.Major, _ = strconv.Atoi(m[1])
+	precision := 1
+	if m[2] != "" {
+		low.Minor, _ = strconv.Atoi(m[2])
+		precision++
+	}
+	if m[3] != "" {
+		low.Patch, _ = strconv.Atoi(m[3])
+		precision++
+	}
+	if low.Prerelease != "" && precision < 3 {
+		return Version{}, Version{}, 0, fmt.Errorf(
+			"prerelease requires a full version: %q", s)
+	}
+	high := Version{Major: low.Major + 1}
+	switch precision {
+	case 2:
+		high = Version{Major: low.Major, Minor: low.Minor + 1}
+	case 3:
+		high = Version{Major: low.Major, Minor: low.Minor, Patch: low.Patch + 1}
+	}
+	return low, high, precision, nil
+}
+
+// Since returns the releases with a version greater than or equal to the
+// possibly partial version, so "0.10" keeps every 0.10.x release and newer.
+// Channels like nightly are newer than any version and are always kept.
+func (rs *Releases) Since(version string) (Releases, error) {
+	low, _, _, err := parsePartial(version)
+	if err != nil {
+		return nil, err
+	}
+	releases := Releases{}
+	for _, info := range *rs {
+		if !info.Version().Less(low) {
+			releases = append(releases, info)
+		}
+	}
+	return releases, nil
+}
+
+// Check returns nil if the version satisfies the constraint, otherwise an
+// error describing why it was rejected.
+func (c Constraint) Check(v Version) error {
+	if c.channel != "" {
+		if v.Channel != c.channel {
+			return fmt.Errorf("not the %s channel", c.channel)
+		}
+		return nil
+	}
+	if v.Channel != "" {
+		return fmt.Errorf("%s channel excluded", v.Channel)
+	}
+	if v.IsPrerelease() && !c.allowPrerelease {
+		return fmt.Errorf("prerelease excluded")
+	}
+	for _, cmp := range c.comparators {
+		if !cmp.match(v) {
+			return fmt.Errorf("does not satisfy %s", cmp)
+		}
+	}
+	return nil
+}
+
+// String returns the normalized form of the constraint.
+func (c Constraint) String() string {
+	if c.channel != "" {
+		return c.channel
+	}
+	if len(c.comparators) == 0 {
+		return "*"
+	}
+	parts := []string{}
+	for _, cmp := range c.comparators {
+		parts = append(parts, cmp.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// Candidate records the outcome of evaluating one release while resolving a
+// query.
+type Candidate struct {
+	Info     Info
+	Selected bool
+	// Reason explains why the candidate was rejected, it is empty for the
+	// selected release.
+	Reason string
+}
+
+// Resolution describes how a query was resolved against the releases.
+type Resolution struct {
+	Query      string
+	Constraint Constraint
+	Selected   *Info
+	Candidates []Candidate
+}
+
+// Resolve finds the newest release matching the query. Besides the
+// constraint expressions accepted by ParseConstraint, the query may be one of
+// the "stable", "nightly", "latest", "lts" or "oldstable" aliases. The
+// returned Resolution lists every release considered, even when no release
+// matches and an error is returned.
+func (rs *Releases) Resolve(query string) (*Resolution, error) {
+	res := &Resolution{Query: strings.TrimSpace(query)}
+	c, err := rs.constraintFor(res.Query)
+	if err != nil {
+		return res, err
+	}
+	res.Constraint = c
+
+	var selected *Info
+	for _, info := range *rs {
+		if err := c.Check(info.Version()); err != nil {
+			res.Candidates = append(res.Candidates,
+				Candidate{Info: info, Reason: err.Error()})
+			continue
+		}
+		if selected == nil || selected.Version().Less(info.Version()) {
+			selected = &info
+		}
+		res.Candidates = append(res.Candidates, Candidate{Info: info})
+	}
+	if selected == nil {
+		return res, fmt.Errorf("no release matches %s", res.Query)
+	}
+	res.Selected = selected
+	for i, candidate := range res.Candidates {
+		if candidate.Reason != "" {
+			continue
+		}
+		if candidate.Info.Version().Equal(selected.Version()) {
+			res.Candidates[i].Selected = true
+			continue
+		}
+		res.Candidates[i].Reason = fmt.Sprintf("older than %s",
+			selected.CleanTagName())
+	}
+	return res, nil
+}
+
+// constraintFor expands the aliases that depend on the known releases and
+// parses any other query as a constraint.
+func (rs *Releases) constraintFor(query string) (Constraint, error) {
+	switch query {
+	case AliasLatest:
+		return Constraint{raw: query}, nil
+	case ChannelStable:
+		stable, err := rs.stable()
+		if err != nil {
+			return Constraint{}, err
+		}
+		return ParseConstraint(stable.Version().String())
+	case AliasLTS, AliasOldStable:
+		stable, err := rs.stable()
+		if err != nil {
+			return Constraint{}, err
+		}
+		sv := stable.Version()
+		var line *Version
+		for _, info := range *rs {
+			v := info.Version()
+			if v.Channel != "" || v.IsPrerelease() {
+				continue
+			}
+			if v.Major > sv.Major ||
+				(v.Major == sv.Major && v.Minor >= sv.Minor) {
+				continue
+			}
+			if line == nil || line.Less(v) {
+				line = &v
+			}
+		}
+		if line == nil {
+			return Constraint{}, fmt.Errorf(
+				"no release line precedes the stable release %s",
+				stable.CleanTagName())
+		}
+		return ParseConstraint(fmt.Sprintf("%d.%d", line.Major, line.Minor))
+	}
+	return ParseConstraint(query)
+}
+
+// stable returns the release marked as stable by Process.
+func (rs *Releases) stable() (*Info, error) {
+	for _, info := range *rs {
+		if info.Stable {
+			return &info, nil
+		}
+	}
+	return nil, fmt.Errorf("no stable release found")
+}
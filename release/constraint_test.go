@@ -0,0 +1,139 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraint(t *testing.T) {
+
+	t.Run("should match constraint expressions", func(t *testing.T) {
+		cases := []struct {
+			constraint string
+			version    string
+			match      bool
+		}{
+			{"0.10", "0.10.4", true},
+			{"0.10", "0.11.0", false},
+			{"0.10.0", "0.10.0", true},
+			{"v0.10.0", "0.10.1", false},
+			{"^0.10.0", "0.10.4", true},
+			{"^0.10.0", "0.11.0", false},
+			{"^1.2.0", "1.9.0", true},
+			{"^1.2.0", "2.0.0", false},
+			{"~0.10.1", "0.10.0", false},
+			{"~0.10.1", "0.10.3", true},
+			{">=0.9,<0.11", "0.9.0", true},
+			{">=0.9,<0.11", "0.10.4", true},
+			{">=0.9,<0.11", "0.11.0", false},
+			{">= 0.9, < 0.11", "0.8.0", false},
+			{"<=0.10", "0.10.4", true},
+			{">0.10", "0.10.4", false},
+			{">0.10", "0.11.0", true},
+			{"0.10", "0.10.0-rc.1", false},
+			{"0.11.0-rc.1", "0.11.0-rc.1", true},
+			{"0.11", "nightly", false},
+			{"nightly", "nightly", true},
+		}
+		for _, c := range cases {
+			constraint, err := ParseConstraint(c.constraint)
+			if err != nil {
+				t.Fatalf("failed to parse constraint %s: %v", c.constraint, err)
+			}
+			err = constraint.Check(MustParseVersion(c.version))
+			assert.Equal(t, c.match, err == nil, "%s against %s: %v",
+				c.constraint, c.version, err)
+		}
+	})
+
+	t.Run("should reject invalid constraints", func(t *testing.T) {
+		for _, s := range []string{"", "foo", ">=", "0.x", "!=0.10",
+			"0.10-rc.1", "=>0.10"} {
+			_, err := ParseConstraint(s)
+			assert.Error(t, err, s)
+		}
+	})
+}
+
+func TestReleasesResolve(t *testing.T) {
+	data := []byte(`[
+		{"tag_name": "nightly", "name": "Nvim development (prerelease) build",
+			"prerelease": true},
+		{"tag_name": "v0.11.5", "name": "Nvim 0.11.5"},
+		{"tag_name": "stable", "name": "Nvim 0.11.5"},
+		{"tag_name": "v0.11.4", "name": "Nvim 0.11.4"},
+		{"tag_name": "v0.10.4", "name": "Nvim 0.10.4"},
+		{"tag_name": "v0.10.3", "name": "Nvim 0.10.3"},
+		{"tag_name": "v0.9.5", "name": "Nvim 0.9.5"}
+	]`)
+
+	releases := Releases{}
+	err := releases.Process(data, "0.7.0", nil)
+	if err != nil {
+		t.Fatalf("failed to process releases: %v", err)
+	}
+
+	t.Run("should resolve aliases and constraints to the newest match", func(t *testing.T) {
+		cases := map[string]string{
+			"latest":      "0.11.5",
+			"stable":      "0.11.5",
+			"nightly":     "nightly",
+			"lts":         "0.10.4",
+			"oldstable":   "0.10.4",
+			"0.10":        "0.10.4",
+			"^0.10.0":     "0.10.4",
+			">=0.9,<0.11": "0.10.4",
+			"<0.10":       "0.9.5",
+			"0.11.4":      "0.11.4",
+		}
+		for query, expected := range cases {
+			info, err := releases.Get(query)
+			if err != nil {
+				t.Fatalf("failed to get %s: %v", query, err)
+			}
+			assert.Equal(t, expected, info.CleanTagName(), query)
+		}
+	})
+
+	t.Run("should explain why candidates were rejected", func(t *testing.T) {
+		res, err := releases.Resolve("0.10")
+		assert.NoError(t, err)
+		reasons := map[string]string{}
+		for _, candidate := range res.Candidates {
+			reasons[candidate.Info.CleanTagName()] = candidate.Reason
+			if candidate.Selected {
+				assert.Equal(t, "0.10.4", candidate.Info.CleanTagName())
+			}
+		}
+		assert.Equal(t, "nightly channel excluded", reasons["nightly"])
+		assert.Equal(t, "does not satisfy <0.11.0", reasons["0.11.5"])
+		assert.Equal(t, "older than 0.10.4", reasons["0.10.3"])
+		assert.Equal(t, "does not satisfy >=0.10.0", reasons["0.9.5"])
+		assert.Equal(t, "", reasons["0.10.4"])
+	})
+
+	t.Run("should keep releases since a partial version", func(t *testing.T) {
+		since, err := releases.Since("0.10.4")
+		assert.NoError(t, err)
+		got := []string{}
+		for _, info := range since {
+			got = append(got, info.CleanTagName())
+		}
+		assert.Equal(t, []string{"nightly", "0.11.5", "0.11.4", "0.10.4"}, got)
+
+		since, err = releases.Since("0.10")
+		assert.NoError(t, err)
+		assert.Len(t, since, 5)
+
+		_, err = releases.Since("latest")
+		assert.Error(t, err)
+	})
+
+	t.Run("should fail when nothing matches", func(t *testing.T) {
+		res, err := releases.Resolve("0.8")
+		assert.Error(t, err)
+		assert.Nil(t, res.Selected)
+		assert.Len(t, res.Candidates, len(releases))
+	})
+}
@@ -0,0 +1,301 @@
+// Package release models the Neovim releases published on GitHub, their
+// versions, the constraints resolving queries to them and the records kept
+// for installed releases. Its types are used by the installer API.
+package release
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/candango/iook/pathx"
+	"github.com/candango/nvimm/internal/logging"
+)
+
+// Releases represents a list of GitHub release information.
+type Releases []Info
+
+// Get retrieves the Info for a specific release. The release may be an exact
+// version, one of the "stable", "nightly", "latest" or "lts" aliases or a
+// constraint such as "0.10", "^0.10.0" or ">=0.9,<0.11", in which case the
+// newest matching release is returned. See Resolve for details on how the
+// release was chosen. If no release matches, it returns an error.
+func (rs *Releases) Get(release string) (*Info, error) {
+	res, err := rs.Resolve(release)
+	if err != nil {
+		return nil, fmt.Errorf("release %s does not exists: %w", release, err)
+	}
+	return res.Selected, nil
+}
+
+// Installed returns a list of releases that are present in the specified
+// path, sorted from the newest to the oldest version.
+func (rs *Releases) Installed(path string) []Info {
+	releases := *rs
+	installed := Releases{}
+	for _, info := range releases {
+		if pathx.Exists(filepath.Join(path, info.CleanTagName())) {
+			installed = append(installed, info)
+		}
+	}
+	installed.SortNewestFirst()
+	return installed
+}
+
+// Available returns a list of releases that are not present in the installed
+// releases.
+func (rs *Releases) Available(installed []Info) []Info {
+	releases := *rs
+	installedDict := map[string]bool{}
+	for _, info := range installed {
+		installedDict[info.CleanTagName()] = true
+	}
+	available := []Info{}
+	for _, info := range releases {
+		ok := installedDict[info.CleanTagName()]
+		if !ok {
+			available = append(available, info)
+		}
+	}
+	return available
+}
+
+// ErrInvalidListing is returned by ValidateListing when the data is not a
+// releases listing.
+var ErrInvalidListing = errors.New("invalid releases listing")
+
+// ValidateListing checks that the data is a releases listing as returned by
+// the GitHub API, a JSON array of releases with their tag names. Truncated
+// data, error payloads like {"message": "API rate limit exceeded"} and empty
+// listings are rejected.
+func ValidateListing(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return fmt.Errorf("%w: empty data", ErrInvalidListing)
+	}
+	if data[0] == '{' {
+		payload := struct {
+			Message string `json:"message"`
+		}{}
+		if err := json.Unmarshal(data, &payload); err == nil &&
+			payload.Message != "" {
+			return fmt.Errorf("%w: error payload %q", ErrInvalidListing,
+				payload.Message)
+		}
+		return fmt.Errorf("%w: object found instead of an array",
+			ErrInvalidListing)
+	}
+	entries := []struct {
+		TagName string `json:"tag_name"`
+	}{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidListing, err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("%w: no releases", ErrInvalidListing)
+	}
+	for i, entry := range entries {
+		if entry.TagName == "" {
+			return fmt.Errorf("%w: release %d has no tag name",
+				ErrInvalidListing, i)
+		}
+	}
+	return nil
+}
+
+// Process unmarshals the provided JSON data into the Releases struct. It also
+// identifies the stable release and marks the corresponding Info entries
+// accordingly. Releases with tags that are not valid versions are discarded
+// and the result is sorted from the newest to the oldest version. Releases
+// older than minRelease are discarded too, with a debug record sent to the
+// logger, which may be nil.
+func (rs *Releases) Process(data []byte, minRelease string,
+	logger *slog.Logger) error {
+	err := json.Unmarshal(data, &rs)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal releases: %w", err)
+	}
+
+	minVersion, err := ParseVersion(minRelease)
+	if err != nil {
+		return fmt.Errorf("invalid minimal release: %w", err)
+	}
+
+	log := logger
+	if log == nil {
+		log = logging.Discard()
+	}
+	releases := (*rs)[:0]
+	var stable Info
+	for _, info := range *rs {
+		v, err := ParseVersion(info.TagName)
+		if err != nil {
+			log.Debug("skipping release with invalid tag", "tag",
+				info.TagName, "error", err)
+			continue
+		}
+		if v.IsStable() {
+			stable = info
+			continue
+		}
+
+		if v.Less(minVersion) {
+			log.Debug("skipping release older than the minimal release",
+				"tag", info.TagName, "min_release", minRelease)
+			continue
+		}
+
+		if v.Less(checksumDigestVersion) {
+			checksums := info.ChecksumsFromBody()
+			for i, asset := range info.Assets {
+				digest, ok := checksums[asset.Name]
+				if ok {
+					asset.Digest = fmt.Sprintf("sha256:%s", digest)
+					info.Assets[i] = asset
+				}
+			}
+		}
+		releases = append(releases, info)
+	}
+
+	for i, info := range releases {
+		if info.Name == stable.Name {
+			info.Stable = true
+			releases[i] = info
+		}
+	}
+	releases.SortNewestFirst()
+	*rs = releases
+	log.Debug("releases processed", "count", len(releases))
+	return nil
+}
+
+// Asset represents a GitHub release asset.
+type Asset struct {
+	Id                 float64   `json:"id"`
+	NodeId             string    `json:"node_id"`
+	Name               string    `json:"name"`
+	Label              string    `json:"label"`
+	State              string    `json:"state"`
+	ContentType        string    `json:"content_type"`
+	Size               float64   `json:"size"`
+	Digest             string    `json:"digest"`
+	BrowserDownloadUrl string    `json:"browser_download_url"`
+	DownloadCount      float64   `json:"download_count"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	Uploader           User      `json:"uploader"`
+}
+
+// Info represents a GitHub release information.
+type Info struct {
+	Id              float64   `json:"id"`
+	Author          User      `json:"author"`
+	Assets          []Asset   `json:"assets"`
+	Body            string    `json:"body"`
+	Name            string    `json:"name"`
+	TarballUrl      string    `json:"tarball_url"`
+	ZipballUrl      string    `json:"zipball_url"`
+	Url             string    `json:"url"`
+	NodeId          string    `json:"node_id"`
+	Immutable       bool      `json:"immutable"`
+	Prerelease      bool      `json:"prerelease"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	PublishedAt     time.Time `json:"published_at"`
+	TagName         string    `json:"tag_name"`
+	Draft           bool      `json:"draft"`
+	AssetsUrl       string    `json:"assets_url"`
+	UploadUrl       string    `json:"upload_url"`
+	HtmlUrl         string    `json:"html_url"`
+	TargetCommitish string    `json:"target_commitish"`
+	Stable          bool
+	// Reactions   []Reaction `json:"reactions"`
+}
+
+// CleanTagName returns the tag name without the "v" prefix. If the tag name is
+// "nightly", it is returned as is.
+func (i *Info) CleanTagName() string {
+	if i.TagName == ChannelNightly {
+		return i.TagName
+	}
+	return strings.TrimPrefix(i.TagName, "v")
+}
+
+// Version returns the parsed version of the release tag. Tags that cannot be
+// parsed result in the zero Version, Process discards those releases.
+func (i *Info) Version() Version {
+	v, _ := ParseVersion(i.TagName)
+	return v
+}
+
+// VersionLess compares the release version against the version string v.
+// Returns true if the release version is less than the reference version.
+// Channels such as nightly and stable are never less than a numbered
+// version, and an invalid reference version is never greater than anything.
+//
+// Example: VersionLess("0.11.3") returns true when i.CleanTagName() is
+// "0.11.2".
+func (i *Info) VersionLess(v string) bool {
+	ref, err := ParseVersion(v)
+	if err != nil {
+		return false
+	}
+	return i.Version().Less(ref)
+}
+
+// checksumDigestVersion is the first release publishing asset digests through
+// the GitHub API, older releases list their checksums in the release body.
+var checksumDigestVersion = MustParseVersion("0.11.3")
+
+var checksumRe = regexp.MustCompile(`([a-f0-9]{64})\s+([^\s]+)`)
+
+func (i *Info) ChecksumsFromBody() map[string]string {
+	result := make(map[string]string)
+	matches := checksumRe.FindAllStringSubmatch(i.Body, -1)
+	for _, m := range matches {
+		result[m[2]] = m[1]
+	}
+	return result
+}
+
+// Reaction represents a GitHub reaction to a release.
+type Reaction struct {
+	Url        string `json:"url"`
+	Confused   string `json:"confused"`
+	Eyes       string `json:"eyes"`
+	Heart      string `json:"heart"`
+	Hooray     string `json:"hooray"`
+	Laugh      string `json:"laugh"`
+	MinusOne   string `json:"-1"`
+	PlusOne    string `json:"+1"`
+	Rocket     string `json:"rocket"`
+	TotalCount string `json:"total_count"`
+}
+
+// User represents a GitHub user.
+type User struct {
+	Id                float64 `json:"id"`
+	Login             string  `json:"login"`
+	NodeId            string  `json:"node_id"`
+	AvatarUrl         string  `json:"avatar_url"`
+	GravatarId        string  `json:"gravatar_id"`
+	Url               string  `json:"url"`
+	HtmlUrl           string  `json:"html_url"`
+	FollowersUrl      string  `json:"followers_url"`
+	FollowingUrl      string  `json:"following_url"`
+	GistsUrl          string  `json:"gists_url"`
+	SubscriptionsUrl  string  `json:"subscriptions_url"`
+	OrganizationsUrl  string  `json:"organizations_url"`
+	ReposUrl          string  `json:"repos_url"`
+	EventsUrl         string  `json:"events_url"`
+	RecievedEventsUrl string  `json:"recieved_events_url"`
+	Type              string  `json:"type"`
+	SiteAdmin         bool    `json:"site_admin"`
+}
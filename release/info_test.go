@@ -0,0 +1,32 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateListing(t *testing.T) {
+
+	t.Run("should accept a releases listing", func(t *testing.T) {
+		assert.NoError(t, ValidateListing([]byte(
+			` [{"tag_name": "v0.11.5"}, {"tag_name": "nightly"}]`+"\n")))
+	})
+
+	t.Run("should reject corrupt listings", func(t *testing.T) {
+		cases := map[string]string{
+			"":                                       "empty data",
+			`[{"tag_name": "v0.11.5"}, {"tag_na`:     "unexpected end of JSON input",
+			`{"message": "API rate limit exceeded"}`: `error payload "API rate limit exceeded"`,
+			`{"tag_name": "v0.11.5"}`:                "object found instead of an array",
+			`[]`:                                     "no releases",
+			`[{"tag_name": "v0.11.5"}, {"name": "x"}]`: "release 1 has no tag name",
+			`<html>Bad Gateway</html>`:                 "invalid character",
+		}
+		for data, message := range cases {
+			err := ValidateListing([]byte(data))
+			assert.ErrorIs(t, err, ErrInvalidListing, data)
+			assert.ErrorContains(t, err, message, data)
+		}
+	})
+}
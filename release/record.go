@@ -0,0 +1,119 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// InstallRecordFile is the name of the file storing the InstallRecord inside
+// an installed release directory.
+const InstallRecordFile = ".nvimm.json"
+
+var (
+	commitRe      = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+	buildCommitRe = regexp.MustCompile(`NVIM v\S+\+g([0-9a-f]{7,40})`)
+)
+
+// InstallRecord describes the release build that was installed. It allows
+// builds published under the same tag, like nightly, to be told apart.
+type InstallRecord struct {
+	TagName         string    `json:"tag_name"`
+	TargetCommitish string    `json:"target_commitish"`
+	Commit          string    `json:"commit,omitempty"`
+	PublishedAt     time.Time `json:"published_at"`
+	Asset           string    `json:"asset"`
+	Digest          string    `json:"digest"`
+	InstalledAt     time.Time `json:"installed_at"`
+}
+
+// NewInstallRecord creates a record for the asset of the release being
+// installed at the given time.
+func NewInstallRecord(info *Info, asset *Asset, at time.Time) *InstallRecord {
+	return &InstallRecord{
+		TagName:         info.TagName,
+		TargetCommitish: info.TargetCommitish,
+		Commit:          info.Commit(),
+		PublishedAt:     info.PublishedAt,
+		Asset:           asset.Name,
+		Digest:          asset.Digest,
+		InstalledAt:     at,
+	}
+}
+
+// ReadInstallRecord loads the record stored in the installed release
+// directory. Releases installed before records existed return an error
+// satisfying os.IsNotExist.
+func ReadInstallRecord(dir string) (*InstallRecord, error) {
+	data, err := os.ReadFile(filepath.Join(dir, InstallRecordFile))
+	if err != nil {
+		return nil, err
+	}
+	record := &InstallRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal install record: %w", err)
+	}
+	return record, nil
+}
+
+// Write persists the record into the installed release directory.
+func (r *InstallRecord) Write(dir string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, InstallRecordFile), data, 0644)
+}
+
+// OutdatedBy returns true if info is a newer build of the installed release,
+// which happens when the nightly tag is moved to a new commit.
+func (r *InstallRecord) OutdatedBy(info *Info) bool {
+	commit := info.Commit()
+	if r.Commit != "" && commit != "" {
+		return r.Commit != commit
+	}
+	return info.PublishedAt.After(r.PublishedAt)
+}
+
+// Describe returns a short human readable description of the build, such as
+// "2026-10-17 g1a2b3c4".
+func (r *InstallRecord) Describe() string {
+	return describeBuild(r.PublishedAt, r.Commit)
+}
+
+// DescribeBuild returns a short human readable description of the release
+// build in the same format as InstallRecord.Describe.
+func (i *Info) DescribeBuild() string {
+	return describeBuild(i.PublishedAt, i.Commit())
+}
+
+func describeBuild(publishedAt time.Time, commit string) string {
+	s := publishedAt.Format(time.DateOnly)
+	if commit != "" {
+		s += " g" + shortCommit(commit)
+	}
+	return s
+}
+
+// Commit returns the commit the release was built from. GitHub reports the
+// branch name as target_commitish for the nightly release, in that case the
+// commit is taken from the "NVIM v0.12.0-dev+g<commit>" line of the body.
+func (i *Info) Commit() string {
+	if commitRe.MatchString(i.TargetCommitish) {
+		return i.TargetCommitish
+	}
+	if m := buildCommitRe.FindStringSubmatch(i.Body); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 9 {
+		return commit[:9]
+	}
+	return commit
+}
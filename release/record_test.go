@@ -0,0 +1,56 @@
+package release
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallRecord(t *testing.T) {
+	published := time.Date(2026, 10, 17, 4, 0, 0, 0, time.UTC)
+	info := &Info{
+		TagName:         "nightly",
+		TargetCommitish: "master",
+		PublishedAt:     published,
+		Body:            "```\nNVIM v0.12.0-dev-1720+g8b2e9b2a1\nBuild type: Release\n```",
+	}
+	asset := &Asset{Name: "nvim-linux-x86_64.tar.gz", Digest: "sha256:abc"}
+
+	t.Run("should take the commit from the body when the target is a branch", func(t *testing.T) {
+		assert.Equal(t, "8b2e9b2a1", info.Commit())
+		assert.Equal(t, "1a2b3c4d", (&Info{TargetCommitish: "1a2b3c4d"}).Commit())
+	})
+
+	t.Run("should write and read the record", func(t *testing.T) {
+		dir := t.TempDir()
+		record := NewInstallRecord(info, asset, published.Add(time.Hour))
+		if err := record.Write(dir); err != nil {
+			t.Fatalf("failed to write install record: %v", err)
+		}
+		got, err := ReadInstallRecord(dir)
+		if err != nil {
+			t.Fatalf("failed to read install record: %v", err)
+		}
+		assert.Equal(t, record, got)
+		assert.Equal(t, "2026-10-17 g8b2e9b2a1", got.Describe())
+
+		_, err = ReadInstallRecord(t.TempDir())
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("should detect newer nightly builds", func(t *testing.T) {
+		record := NewInstallRecord(info, asset, published)
+		assert.False(t, record.OutdatedBy(info))
+
+		newer := *info
+		newer.PublishedAt = published.Add(24 * time.Hour)
+		newer.Body = "NVIM v0.12.0-dev-1731+gffe1d2c3b"
+		assert.True(t, record.OutdatedBy(&newer))
+
+		record.Commit = ""
+		newer.Body = ""
+		assert.True(t, record.OutdatedBy(&newer))
+	})
+}
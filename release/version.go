@@ -0,0 +1,207 @@
+package release
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ChannelNightly identifies the rolling nightly build.
+	ChannelNightly = "nightly"
+	// ChannelStable identifies the alias GitHub tag pointing to the current
+	// stable release.
+	ChannelStable = "stable"
+)
+
+var versionRe = regexp.MustCompile(
+	`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?` +
+		`(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+// Version represents a semantic version in the form
+// vMAJOR.MINOR.PATCH[-prerelease][+build]. The "nightly" and "stable" tags
+// are represented as channels and carry no numeric components.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+	Channel    string
+}
+
+// ParseVersion parses a version string. The "v" prefix is optional and the
+// special "nightly" and "stable" channels are accepted as is.
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimSpace(s)
+	if s == ChannelNightly || s == ChannelStable {
+		return Version{Channel: s}, nil
+	}
+	m := versionRe.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	v := Version{Prerelease: m[4], Build: m[5]}
+	// The regexp guarantees the numeric groups are valid integers.
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, nil
+}
+
+// MustParseVersion is like ParseVersion but panics if the version cannot be
+// parsed. It is intended for constants known at compile time.
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// IsNightly returns true if the version represents the nightly channel.
+func (v Version) IsNightly() bool {
+	return v.Channel == ChannelNightly
+}
+
+// IsStable returns true if the version represents the stable channel alias.
+func (v Version) IsStable() bool {
+	return v.Channel == ChannelStable
+}
+
+// IsPrerelease returns true if the version has a prerelease component or is
+// the nightly channel.
+func (v Version) IsPrerelease() bool {
+	return v.Prerelease != "" || v.IsNightly()
+}
+
+// String returns the version without the "v" prefix, which is the format
+// used to name installation directories.
+func (v Version) String() string {
+	if v.Channel != "" {
+		return v.Channel
+	}
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Tag returns the version formatted as a GitHub tag name.
+func (v Version) Tag() string {
+	if v.Channel != "" {
+		return v.Channel
+	}
+	return "v" + v.String()
+}
+
+// Compare returns -1, 0 or 1 if v is respectively lower, equal or greater
+// than o. Build metadata is ignored as mandated by the semver specification.
+// Channels are ordered after every numbered version, with nightly being the
+// greatest.
+func (v Version) Compare(o Version) int {
+	if r := compareInt(channelRank(v.Channel), channelRank(o.Channel)); r != 0 {
+		return r
+	}
+	if v.Channel != "" {
+		return 0
+	}
+	if r := compareInt(v.Major, o.Major); r != 0 {
+		return r
+	}
+	if r := compareInt(v.Minor, o.Minor); r != 0 {
+		return r
+	}
+	if r := compareInt(v.Patch, o.Patch); r != 0 {
+		return r
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// Less returns true if v is lower than o.
+func (v Version) Less(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+// Equal returns true if v and o have the same precedence.
+func (v Version) Equal(o Version) bool {
+	return v.Compare(o) == 0
+}
+
+func channelRank(channel string) int {
+	switch channel {
+	case ChannelStable:
+		return 1
+	case ChannelNightly:
+		return 2
+	}
+	return 0
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease follows the semver precedence rules: a version without
+// prerelease is greater than one with it, numeric identifiers are compared
+// numerically and are lower than alphanumeric ones.
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if r := compareInt(an, bn); r != 0 {
+				return r
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if r := strings.Compare(as[i], bs[i]); r != 0 {
+				return r
+			}
+		}
+	}
+	return compareInt(len(as), len(bs))
+}
+
+// Versions is a sortable list of versions in ascending order.
+type Versions []Version
+
+func (vs Versions) Len() int           { return len(vs) }
+func (vs Versions) Less(i, j int) bool { return vs[i].Less(vs[j]) }
+func (vs Versions) Swap(i, j int)      { vs[i], vs[j] = vs[j], vs[i] }
+
+// SortNewestFirst sorts the releases by version in descending order, keeping
+// the relative order of entries with the same precedence.
+func (rs Releases) SortNewestFirst() {
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[j].Version().Less(rs[i].Version())
+	})
+}
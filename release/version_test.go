@@ -0,0 +1,131 @@
+package release
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersion(t *testing.T) {
+
+	t.Run("should parse versions with prerelease and build metadata", func(t *testing.T) {
+		v, err := ParseVersion("v0.12.0-dev.1+g1a2b3c")
+		if err != nil {
+			t.Fatalf("failed to parse version: %v", err)
+		}
+		assert.Equal(t, 0, v.Major)
+		assert.Equal(t, 12, v.Minor)
+		assert.Equal(t, 0, v.Patch)
+		assert.Equal(t, "dev.1", v.Prerelease)
+		assert.Equal(t, "g1a2b3c", v.Build)
+		assert.Equal(t, "0.12.0-dev.1+g1a2b3c", v.String())
+		assert.Equal(t, "v0.12.0-dev.1+g1a2b3c", v.Tag())
+	})
+
+	t.Run("should parse channels", func(t *testing.T) {
+		v, err := ParseVersion("nightly")
+		assert.NoError(t, err)
+		assert.True(t, v.IsNightly())
+		assert.True(t, v.IsPrerelease())
+
+		v, err = ParseVersion("stable")
+		assert.NoError(t, err)
+		assert.True(t, v.IsStable())
+		assert.Equal(t, "stable", v.String())
+	})
+
+	t.Run("should reject invalid versions", func(t *testing.T) {
+		for _, s := range []string{"", "v", "0.11", "0.11.x", "01.2.3",
+			"vv0.1.0", "0.1.0-", "latest"} {
+			_, err := ParseVersion(s)
+			assert.Error(t, err, s)
+		}
+	})
+
+	t.Run("should compare versions", func(t *testing.T) {
+		assert.True(t, MustParseVersion("0.9.5").Less(MustParseVersion("0.10.0")))
+		assert.True(t, MustParseVersion("0.11.0-rc.1").Less(MustParseVersion("0.11.0")))
+		assert.True(t, MustParseVersion("0.11.0-rc.2").Less(MustParseVersion("0.11.0-rc.10")))
+		assert.True(t, MustParseVersion("0.11.0-1").Less(MustParseVersion("0.11.0-alpha")))
+		assert.True(t, MustParseVersion("0.11.0-alpha").Less(MustParseVersion("0.11.0-alpha.1")))
+		assert.True(t, MustParseVersion("0.11.5").Less(MustParseVersion("stable")))
+		assert.True(t, MustParseVersion("stable").Less(MustParseVersion("nightly")))
+		assert.True(t, MustParseVersion("0.11.5+a").Equal(MustParseVersion("v0.11.5+b")))
+	})
+
+	t.Run("should sort versions", func(t *testing.T) {
+		vs := Versions{
+			MustParseVersion("nightly"),
+			MustParseVersion("0.10.0"),
+			MustParseVersion("0.9.5"),
+			MustParseVersion("0.10.0-rc.1"),
+			MustParseVersion("0.11.5"),
+		}
+		sort.Sort(vs)
+		got := []string{}
+		for _, v := range vs {
+			got = append(got, v.String())
+		}
+		assert.Equal(t, []string{"0.9.5", "0.10.0-rc.1", "0.10.0", "0.11.5",
+			"nightly"}, got)
+	})
+}
+
+func TestInfoVersion(t *testing.T) {
+
+	t.Run("should only strip the v prefix from the tag name", func(t *testing.T) {
+		info := Info{TagName: "v0.11.5-dev"}
+		assert.Equal(t, "0.11.5-dev", info.CleanTagName())
+		info = Info{TagName: "nightly"}
+		assert.Equal(t, "nightly", info.CleanTagName())
+	})
+
+	t.Run("should compare with version strings", func(t *testing.T) {
+		info := Info{TagName: "v0.10.3"}
+		assert.True(t, info.VersionLess("0.10.4"))
+		assert.False(t, info.VersionLess("0.10.3"))
+		assert.False(t, info.VersionLess("invalid"))
+		info = Info{TagName: "nightly"}
+		assert.False(t, info.VersionLess("0.10.4"))
+	})
+}
+
+func TestReleasesProcess(t *testing.T) {
+	data := []byte(`[
+		{"tag_name": "nightly", "name": "Nvim development (prerelease) build"},
+		{"tag_name": "v0.9.5", "name": "Nvim 0.9.5"},
+		{"tag_name": "v0.11.5", "name": "Nvim 0.11.5"},
+		{"tag_name": "stable", "name": "Nvim 0.11.5"},
+		{"tag_name": "v0.6.1", "name": "Nvim 0.6.1"},
+		{"tag_name": "not-a-version", "name": "Broken"},
+		{"tag_name": "v0.10.4", "name": "Nvim 0.10.4"}
+	]`)
+
+	releases := Releases{}
+	err := releases.Process(data, "0.7.0", nil)
+	if err != nil {
+		t.Fatalf("failed to process releases: %v", err)
+	}
+
+	t.Run("should sort releases and drop invalid or old ones", func(t *testing.T) {
+		got := []string{}
+		for _, info := range releases {
+			got = append(got, info.CleanTagName())
+		}
+		assert.Equal(t, []string{"nightly", "0.11.5", "0.10.4", "0.9.5"}, got)
+	})
+
+	t.Run("should get releases by version or channel", func(t *testing.T) {
+		info, err := releases.Get("stable")
+		assert.NoError(t, err)
+		assert.Equal(t, "v0.11.5", info.TagName)
+
+		info, err = releases.Get("v0.10.4")
+		assert.NoError(t, err)
+		assert.Equal(t, "v0.10.4", info.TagName)
+
+		_, err = releases.Get("0.8.0")
+		assert.Error(t, err)
+	})
+}